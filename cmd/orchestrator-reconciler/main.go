@@ -26,5 +26,5 @@ import (
 )
 
 func main() {
-	sharedmain.Main("orchestrator-reconciler", orchestrator.NewReconciler)
+	sharedmain.Main("orchestrator-reconciler", orchestrator.NewReconciler, orchestrator.NewTaskRunReconciler)
 }
@@ -0,0 +1,81 @@
+#!/usr/bin/env bash
+# Copyright 2026 The Tekton Authors
+#
+# Regenerate the deepcopy functions, clientset, listers, informers and knative
+# injection helpers for the armada API types.
+#
+# The generators are expected to be in GOBIN (or GOPATH/bin):
+#   go install k8s.io/code-generator/cmd/{client-gen,lister-gen,informer-gen,deepcopy-gen}@v0.31.4
+#   go install knative.dev/pkg/codegen/cmd/injection-gen
+set -eufo pipefail
+
+TOPDIR=$(git rev-parse --show-toplevel)
+cd "${TOPDIR}" || exit 1
+
+PREFIX=${GOBIN:-$(go env GOPATH)/bin}
+MODULE=github.com/openshift-pipelines/tekton-armadas
+APIS_PKG=${MODULE}/pkg/apis
+CLIENT_PKG=${MODULE}/pkg/client
+GROUPS_VERSIONS=(armada/v1alpha1)
+HEADER=${TOPDIR}/hack/boilerplate/boilerplate.go.txt
+
+FQ_APIS=()
+for gv in "${GROUPS_VERSIONS[@]}"; do
+  FQ_APIS+=("${APIS_PKG}/${gv}")
+done
+
+echo "Generating deepcopy funcs"
+"${PREFIX}/deepcopy-gen" \
+  --go-header-file "${HEADER}" \
+  --output-file zz_generated.deepcopy.go \
+  --bounding-dirs "${APIS_PKG}" \
+  "${FQ_APIS[@]}"
+
+rm -rf pkg/client/clientset pkg/client/listers pkg/client/informers pkg/client/injection
+
+echo "Generating clientset"
+"${PREFIX}/client-gen" \
+  --go-header-file "${HEADER}" \
+  --clientset-name versioned \
+  --input-base "${APIS_PKG}" \
+  --input "$(
+    IFS=,
+    echo "${GROUPS_VERSIONS[*]}"
+  )" \
+  --output-dir pkg/client/clientset \
+  --output-pkg "${CLIENT_PKG}/clientset"
+
+echo "Generating listers"
+"${PREFIX}/lister-gen" \
+  --go-header-file "${HEADER}" \
+  --output-dir pkg/client/listers \
+  --output-pkg "${CLIENT_PKG}/listers" \
+  "${FQ_APIS[@]}"
+
+echo "Generating informers"
+"${PREFIX}/informer-gen" \
+  --go-header-file "${HEADER}" \
+  --versioned-clientset-package "${CLIENT_PKG}/clientset/versioned" \
+  --listers-package "${CLIENT_PKG}/listers" \
+  --output-dir pkg/client/informers \
+  --output-pkg "${CLIENT_PKG}/informers" \
+  "${FQ_APIS[@]}"
+
+echo "Generating injection"
+OUTPUT_BASE=$(mktemp -d)
+trap 'rm -rf ${OUTPUT_BASE}' EXIT
+"${PREFIX}/injection-gen" \
+  --go-header-file "${HEADER}" \
+  --input-dirs "$(
+    IFS=,
+    echo "${FQ_APIS[*]}"
+  )" \
+  --versioned-clientset-package "${CLIENT_PKG}/clientset/versioned" \
+  --external-versions-informers-package "${CLIENT_PKG}/informers/externalversions" \
+  --listers-package "${CLIENT_PKG}/listers" \
+  --output-package "${CLIENT_PKG}/injection" \
+  --output-base "${OUTPUT_BASE}"
+cp -r "${OUTPUT_BASE}/${CLIENT_PKG}/injection" pkg/client/injection
+
+go mod tidy
+go mod vendor
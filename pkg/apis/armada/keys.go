@@ -0,0 +1,21 @@
+package armada
+
+const (
+	// LabelDispatched is set on the runs created by a minion on behalf of the orchestrator.
+	LabelDispatched = GroupName + "/dispatched"
+	// LabelIdempotencyKey identifies the dispatch the run has been created
+	// for, a dispatch with the same key is not applied twice.
+	LabelIdempotencyKey = GroupName + "/idempotency-key"
+	// LabelTenant is the tenant of the run, the minions mapping the
+	// namespaces per tenant create the run in the namespace of the tenant.
+	LabelTenant = GroupName + "/tenant"
+	// LabelManagedBy is set to ManagedBy on the resources a minion applies,
+	// the existing resources without it are not overwritten.
+	LabelManagedBy = "app.kubernetes.io/managed-by"
+	// ManagedBy is the value of LabelManagedBy on the resources of armada.
+	ManagedBy = "tekton-armadas"
+	// AnnotationSourceNamespace is the namespace of the source run on the orchestrator.
+	AnnotationSourceNamespace = GroupName + "/source-namespace"
+	// AnnotationSourceName is the name of the source run on the orchestrator.
+	AnnotationSourceName = GroupName + "/source-name"
+)
@@ -0,0 +1,49 @@
+/*
+Copyright 2026 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"knative.dev/pkg/apis"
+)
+
+const (
+	// MinionReasonHeartbeat is the reason of the Ready condition of a minion
+	// sending its heartbeats.
+	MinionReasonHeartbeat = "Heartbeat"
+	// MinionReasonHeartbeatMissed is the reason of the Ready condition of a
+	// minion which stopped sending its heartbeats.
+	MinionReasonHeartbeatMissed = "HeartbeatMissed"
+)
+
+var minionCondSet = apis.NewLivingConditionSet()
+
+// MarkReady marks the minion as ready.
+func (ms *MinionStatus) MarkReady() {
+	minionCondSet.Manage(ms).MarkTrue(apis.ConditionReady)
+}
+
+// MarkNotReady marks the minion as not ready with the reason.
+func (ms *MinionStatus) MarkNotReady(reason, messageFormat string, messageA ...interface{}) {
+	minionCondSet.Manage(ms).MarkFalse(apis.ConditionReady, reason, messageFormat, messageA...)
+}
+
+// IsNotReady checks if the minion has been marked as not ready, a minion
+// which never reported is not known to be unready.
+func (ms *MinionStatus) IsNotReady() bool {
+	cond := minionCondSet.Manage(ms).GetCondition(apis.ConditionReady)
+	return cond != nil && cond.IsFalse()
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2026 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+	"knative.dev/pkg/kmeta"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// Minion is a cluster registered to the orchestrator which PipelineRuns can be
+// dispatched to. The labels of the Minion are used for placement.
+type Minion struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec holds the desired state of the Minion.
+	// +optional
+	Spec MinionSpec `json:"spec,omitempty"`
+
+	// Status communicates the observed state of the Minion.
+	// +optional
+	Status MinionStatus `json:"status,omitempty"`
+}
+
+var (
+	_ kmeta.OwnerRefable = (*Minion)(nil)
+	_ duckv1.KRShaped    = (*Minion)(nil)
+)
+
+// MinionMode is how the runs reach the minion.
+type MinionMode string
+
+const (
+	// MinionModePush sends the events to the URL of the minion.
+	MinionModePush MinionMode = "push"
+	// MinionModePull lets the minion poll the orchestrator for its events,
+	// for the minions the orchestrator cannot reach.
+	MinionModePull MinionMode = "pull"
+)
+
+// MinionSpec defines how to reach a minion cluster.
+type MinionSpec struct {
+	// URL is the endpoint of the minion controller the events are sent to,
+	// unused in pull mode.
+	// +optional
+	URL string `json:"url,omitempty"`
+
+	// Mode is how the runs reach the minion, defaults to push.
+	// +optional
+	Mode MinionMode `json:"mode,omitempty"`
+
+	// Capacity is the maximum number of runs, PipelineRuns and TaskRuns,
+	// dispatched to the minion and not done yet, zero means no limit.
+	// +optional
+	Capacity int32 `json:"capacity,omitempty"`
+
+	// Weight is the share of PipelineRuns the minion gets relative to the
+	// other minions with the weighted scheduler, defaults to 1.
+	// +optional
+	Weight int32 `json:"weight,omitempty"`
+
+	// CredentialsRef references a Secret in the namespace of the Minion
+	// holding the credentials used to talk to the minion: the hmac-secret
+	// key to sign the events, the tls.crt and tls.key client certificate
+	// and the ca.crt to verify the minion certificate. The requests of a
+	// minion without a hmac-secret are rejected.
+	// +optional
+	CredentialsRef *corev1.LocalObjectReference `json:"credentialsRef,omitempty"`
+}
+
+// MinionStatus communicates the observed state of the Minion.
+type MinionStatus struct {
+	duckv1.Status `json:",inline"`
+
+	// RunningPipelineRuns is the number of PipelineRuns currently running on
+	// the minion as reported by the minion.
+	// +optional
+	RunningPipelineRuns int32 `json:"runningPipelineRuns,omitempty"`
+
+	// PendingPipelineRuns is the number of PipelineRuns created on the minion
+	// which have not started yet.
+	// +optional
+	PendingPipelineRuns int32 `json:"pendingPipelineRuns,omitempty"`
+
+	// Version is the version of the minion controller.
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// TektonVersion is the version of Tekton Pipelines on the minion.
+	// +optional
+	TektonVersion string `json:"tektonVersion,omitempty"`
+
+	// Allocatable is the sum of the allocatable resources of the nodes of
+	// the minion.
+	// +optional
+	Allocatable corev1.ResourceList `json:"allocatable,omitempty"`
+
+	// SchemaVersion is the newest version of the events the minion
+	// supports, the events are sent in the version 1 when it is empty.
+	// +optional
+	SchemaVersion string `json:"schemaVersion,omitempty"`
+
+	// LastHeartbeatTime is when the orchestrator last received a heartbeat
+	// from the minion.
+	// +optional
+	LastHeartbeatTime *metav1.Time `json:"lastHeartbeatTime,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// MinionList is a list of Minion resources.
+type MinionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []Minion `json:"items"`
+}
+
+// IsPull checks if the minion polls the orchestrator for its events.
+func (m *Minion) IsPull() bool {
+	return m.Spec.Mode == MinionModePull
+}
+
+// GetGroupVersionKind implements kmeta.OwnerRefable.
+func (*Minion) GetGroupVersionKind() schema.GroupVersionKind {
+	return SchemeGroupVersion.WithKind("Minion")
+}
+
+// GetConditionSet implements duckv1.KRShaped.
+func (*Minion) GetConditionSet() apis.ConditionSet {
+	return minionCondSet
+}
+
+// GetStatus implements duckv1.KRShaped.
+func (m *Minion) GetStatus() *duckv1.Status {
+	return &m.Status.Status
+}
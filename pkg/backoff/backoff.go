@@ -0,0 +1,90 @@
+package backoff
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// ConfigName is the name of the ConfigMap configuring the dispatch retries.
+	ConfigName = "config-dispatch"
+
+	// InitialDelayKey is the key of the delay before the first retry.
+	InitialDelayKey = "backoff-initial-delay"
+	// MaxDelayKey is the key of the maximum delay between two retries.
+	MaxDelayKey = "backoff-max-delay"
+	// MaxAttemptsKey is the key of the number of attempts after which the
+	// dispatch is given up, 0 retries forever.
+	MaxAttemptsKey = "max-attempts"
+
+	DefaultInitialDelay = 5 * time.Second
+	DefaultMaxDelay     = 5 * time.Minute
+	DefaultMaxAttempts  = 10
+)
+
+// Backoff is an exponential backoff doubling the delay after each attempt.
+type Backoff struct {
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	MaxAttempts  int
+}
+
+// Default returns the backoff used when the ConfigMap does not configure it.
+func Default() Backoff {
+	return Backoff{
+		InitialDelay: DefaultInitialDelay,
+		MaxDelay:     DefaultMaxDelay,
+		MaxAttempts:  DefaultMaxAttempts,
+	}
+}
+
+// NewFromConfigMap returns the backoff configured in the ConfigMap, the keys
+// not set keep their default.
+func NewFromConfigMap(cm *corev1.ConfigMap) (Backoff, error) {
+	b := Default()
+	if v, ok := cm.Data[InitialDelayKey]; ok {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return Backoff{}, fmt.Errorf("invalid %s %q: must be a positive duration", InitialDelayKey, v)
+		}
+		b.InitialDelay = d
+	}
+	if v, ok := cm.Data[MaxDelayKey]; ok {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return Backoff{}, fmt.Errorf("invalid %s %q: must be a positive duration", MaxDelayKey, v)
+		}
+		b.MaxDelay = d
+	}
+	if v, ok := cm.Data[MaxAttemptsKey]; ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return Backoff{}, fmt.Errorf("invalid %s %q: must be a positive integer", MaxAttemptsKey, v)
+		}
+		b.MaxAttempts = n
+	}
+	if b.MaxDelay < b.InitialDelay {
+		return Backoff{}, fmt.Errorf("%s %s is lower than %s %s", MaxDelayKey, b.MaxDelay, InitialDelayKey, b.InitialDelay)
+	}
+	return b, nil
+}
+
+// Delay returns how long to wait after the attempt before retrying.
+func (b Backoff) Delay(attempt int) time.Duration {
+	delay := b.InitialDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= b.MaxDelay {
+			return b.MaxDelay
+		}
+	}
+	return delay
+}
+
+// Exhausted checks if there should be no more attempt after this one.
+func (b Backoff) Exhausted(attempt int) bool {
+	return b.MaxAttempts > 0 && attempt >= b.MaxAttempts
+}
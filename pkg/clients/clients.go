@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"time"
 
+	armadaclientsetversioned "github.com/openshift-pipelines/tekton-armadas/pkg/client/clientset/versioned"
 	"github.com/pkg/errors"
 	tektonvclientsetversioned "github.com/tektoncd/pipeline/pkg/client/clientset/versioned"
 	"k8s.io/client-go/kubernetes"
@@ -21,6 +22,7 @@ const (
 
 type Clients struct {
 	Tekton            tektonvclientsetversioned.Interface
+	Armada            armadaclientsetversioned.Interface
 	Kube              kubernetes.Interface
 	HTTP              http.Client
 	ClientInitialized bool
@@ -55,6 +57,14 @@ func (c *Clients) tektonClient(config *rest.Config) (tektonvclientsetversioned.I
 	return cs, nil
 }
 
+func (c *Clients) armadaClient(config *rest.Config) (armadaclientsetversioned.Interface, error) {
+	cs, err := armadaclientsetversioned.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
 func NewClients() (*Clients, error) {
 	c := &Clients{}
 
@@ -88,6 +98,11 @@ func NewClients() (*Clients, error) {
 		return nil, err
 	}
 
+	c.Armada, err = c.armadaClient(config)
+	if err != nil {
+		return nil, err
+	}
+
 	c.ClientInitialized = true
 	return c, nil
 }
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/openshift-pipelines/tekton-armadas/pkg/payload"
+	"github.com/openshift-pipelines/tekton-armadas/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	// ArmadaConfigName is the name of the ConfigMap configuring how the
+	// orchestrator talks to the minions.
+	ArmadaConfigName = "config-armada"
+
+	// DefaultMinionURLKey is the key of the URL of the minions in push mode
+	// without one.
+	DefaultMinionURLKey = "default-minion-url"
+	// EventSourceKey is the key of the source of the events sent to the minions.
+	EventSourceKey = "event-source"
+	// RequestTimeoutKey is the key of how long to wait for a minion to
+	// acknowledge an event.
+	RequestTimeoutKey = "request-timeout"
+	// MaxPayloadSizeKey is the key of the size of the largest event sent to a
+	// minion once compressed, as a quantity such as 2Mi.
+	MaxPayloadSizeKey = "max-payload-size"
+	// CompressionKey is the key of the encoding the data of the events is
+	// compressed with, none, gzip or zstd.
+	CompressionKey = "compression"
+	// ChunkSizeKey is the key of the size of the largest request sent to a
+	// minion, the events over it are sent in chunks. Zero disables chunking,
+	// it is at least payload.MinChunkSize otherwise.
+	ChunkSizeKey = "chunk-size"
+	// DispatchThroughSinkKey is the key enabling the dispatch of the events
+	// of the minions in push mode through a Knative Eventing sink.
+	DispatchThroughSinkKey = "dispatch-through-sink"
+	// SinkURLKey is the key of the URL of the sink, the K_SINK environment
+	// variable set by a SinkBinding is used when empty.
+	SinkURLKey = "sink-url"
+	// HeartbeatTimeoutKey is the key of how long a minion stays ready
+	// without sending a heartbeat.
+	HeartbeatTimeoutKey = "heartbeat-timeout"
+
+	DefaultEventSource    = types.EventSource
+	DefaultRequestTimeout = 30 * time.Second
+	DefaultMaxPayloadSize = 2 * 1024 * 1024
+	DefaultCompression    = payload.EncodingNone
+	// DefaultHeartbeatTimeout is three heartbeats at the default interval of
+	// the minions.
+	DefaultHeartbeatTimeout = 90 * time.Second
+)
+
+// Armada is how the orchestrator talks to the minions.
+type Armada struct {
+	DefaultMinionURL string
+	EventSource      string
+	RequestTimeout   time.Duration
+	MaxPayloadSize   int64
+	Compression      string
+	ChunkSize        int64
+
+	// DispatchThroughSink publishes the events to the sink at SinkURL, a
+	// Broker or a Channel, instead of sending them to the minions directly.
+	DispatchThroughSink bool
+	SinkURL             string
+
+	HeartbeatTimeout time.Duration
+}
+
+// DefaultArmada returns the configuration used when the ConfigMap does not
+// configure it.
+func DefaultArmada() *Armada {
+	return &Armada{
+		EventSource:    DefaultEventSource,
+		RequestTimeout: DefaultRequestTimeout,
+		MaxPayloadSize: DefaultMaxPayloadSize,
+		Compression:    DefaultCompression,
+
+		HeartbeatTimeout: DefaultHeartbeatTimeout,
+	}
+}
+
+// NewArmadaFromConfigMap returns the configuration of the ConfigMap, the keys
+// not set keep their default.
+func NewArmadaFromConfigMap(cm *corev1.ConfigMap) (*Armada, error) {
+	a := DefaultArmada()
+	if v, ok := cm.Data[DefaultMinionURLKey]; ok && v != "" {
+		u, err := url.Parse(v)
+		if err != nil || !u.IsAbs() || u.Host == "" {
+			return nil, fmt.Errorf("invalid %s %q: must be an absolute url", DefaultMinionURLKey, v)
+		}
+		a.DefaultMinionURL = v
+	}
+	if v, ok := cm.Data[EventSourceKey]; ok && v != "" {
+		if _, err := url.Parse(v); err != nil {
+			return nil, fmt.Errorf("invalid %s %q: must be a uri reference: %w", EventSourceKey, v, err)
+		}
+		a.EventSource = v
+	}
+	if v, ok := cm.Data[RequestTimeoutKey]; ok {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid %s %q: must be a positive duration", RequestTimeoutKey, v)
+		}
+		a.RequestTimeout = d
+	}
+	if v, ok := cm.Data[MaxPayloadSizeKey]; ok {
+		q, err := resource.ParseQuantity(v)
+		if err != nil || q.Value() <= 0 {
+			return nil, fmt.Errorf("invalid %s %q: must be a positive quantity", MaxPayloadSizeKey, v)
+		}
+		a.MaxPayloadSize = q.Value()
+	}
+	if v, ok := cm.Data[CompressionKey]; ok && v != "" {
+		if !payload.ValidEncoding(v) {
+			return nil, fmt.Errorf("invalid %s %q: must be one of %s, %s or %s", CompressionKey, v, payload.EncodingNone, payload.EncodingGzip, payload.EncodingZstd)
+		}
+		a.Compression = v
+	}
+	if v, ok := cm.Data[ChunkSizeKey]; ok && v != "" {
+		q, err := resource.ParseQuantity(v)
+		if err != nil || q.Value() < 0 || (q.Value() > 0 && q.Value() < payload.MinChunkSize) {
+			return nil, fmt.Errorf("invalid %s %q: must be a quantity of at least %d bytes, zero to disable chunking", ChunkSizeKey, v, payload.MinChunkSize)
+		}
+		a.ChunkSize = q.Value()
+	}
+	if v, ok := cm.Data[DispatchThroughSinkKey]; ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %w", DispatchThroughSinkKey, v, err)
+		}
+		a.DispatchThroughSink = b
+	}
+	if v, ok := cm.Data[SinkURLKey]; ok && v != "" {
+		u, err := url.Parse(v)
+		if err != nil || !u.IsAbs() || u.Host == "" {
+			return nil, fmt.Errorf("invalid %s %q: must be an absolute url", SinkURLKey, v)
+		}
+		a.SinkURL = v
+	}
+	if v, ok := cm.Data[HeartbeatTimeoutKey]; ok {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid %s %q: must be a positive duration", HeartbeatTimeoutKey, v)
+		}
+		a.HeartbeatTimeout = d
+	}
+	return a, nil
+}
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/openshift-pipelines/tekton-armadas/pkg/payload"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestNewArmadaFromConfigMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    map[string]string
+		want    *Armada
+		wantErr bool
+	}{
+		{
+			name: "defaults",
+			want: DefaultArmada(),
+		},
+		{
+			name: "empty values keep the defaults",
+			data: map[string]string{
+				DefaultMinionURLKey: "",
+				EventSourceKey:      "",
+				CompressionKey:      "",
+				ChunkSizeKey:        "",
+				SinkURLKey:          "",
+			},
+			want: DefaultArmada(),
+		},
+		{
+			name: "all keys",
+			data: map[string]string{
+				DefaultMinionURLKey:    "http://minion.armada.svc:8080",
+				EventSourceKey:         "/orchestrator",
+				RequestTimeoutKey:      "1m",
+				MaxPayloadSizeKey:      "8Mi",
+				CompressionKey:         payload.EncodingZstd,
+				ChunkSizeKey:           "512Ki",
+				DispatchThroughSinkKey: "true",
+				SinkURLKey:             "http://broker-ingress.knative-eventing.svc/armada/default",
+				HeartbeatTimeoutKey:    "5m",
+			},
+			want: &Armada{
+				DefaultMinionURL:    "http://minion.armada.svc:8080",
+				EventSource:         "/orchestrator",
+				RequestTimeout:      time.Minute,
+				MaxPayloadSize:      8 * 1024 * 1024,
+				Compression:         payload.EncodingZstd,
+				ChunkSize:           512 * 1024,
+				DispatchThroughSink: true,
+				SinkURL:             "http://broker-ingress.knative-eventing.svc/armada/default",
+				HeartbeatTimeout:    5 * time.Minute,
+			},
+		},
+		{
+			name: "chunking disabled",
+			data: map[string]string{ChunkSizeKey: "0"},
+			want: DefaultArmada(),
+		},
+		{
+			name:    "chunk size under the minimum",
+			data:    map[string]string{ChunkSizeKey: "1Ki"},
+			wantErr: true,
+		},
+		{
+			name:    "relative default minion url",
+			data:    map[string]string{DefaultMinionURLKey: "/minion"},
+			wantErr: true,
+		},
+		{
+			name:    "bad request timeout",
+			data:    map[string]string{RequestTimeoutKey: "thirty seconds"},
+			wantErr: true,
+		},
+		{
+			name:    "empty request timeout",
+			data:    map[string]string{RequestTimeoutKey: ""},
+			wantErr: true,
+		},
+		{
+			name:    "negative request timeout",
+			data:    map[string]string{RequestTimeoutKey: "-1s"},
+			wantErr: true,
+		},
+		{
+			name:    "bad heartbeat timeout",
+			data:    map[string]string{HeartbeatTimeoutKey: "ninety seconds"},
+			wantErr: true,
+		},
+		{
+			name:    "zero heartbeat timeout",
+			data:    map[string]string{HeartbeatTimeoutKey: "0s"},
+			wantErr: true,
+		},
+		{
+			name:    "zero max payload size",
+			data:    map[string]string{MaxPayloadSizeKey: "0"},
+			wantErr: true,
+		},
+		{
+			name:    "bad max payload size",
+			data:    map[string]string{MaxPayloadSizeKey: "two megabytes"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown compression",
+			data:    map[string]string{CompressionKey: "brotli"},
+			wantErr: true,
+		},
+		{
+			name:    "negative chunk size",
+			data:    map[string]string{ChunkSizeKey: "-1Ki"},
+			wantErr: true,
+		},
+		{
+			name:    "bad dispatch through sink",
+			data:    map[string]string{DispatchThroughSinkKey: "sometimes"},
+			wantErr: true,
+		},
+		{
+			name:    "relative sink url",
+			data:    map[string]string{SinkURLKey: "broker"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewArmadaFromConfigMap(&corev1.ConfigMap{Data: tt.data})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewArmadaFromConfigMap() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewArmadaFromConfigMap() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/openshift-pipelines/tekton-armadas/pkg/backoff"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// DispatchConfigName is the name of the ConfigMap configuring the
+	// dispatch retries and the failover of the runs.
+	DispatchConfigName = backoff.ConfigName
+
+	// FailoverGracePeriodKey is the key of how long a minion can be unready
+	// before its runs are failed over.
+	FailoverGracePeriodKey = "failover-grace-period"
+
+	DefaultFailoverGracePeriod = 5 * time.Minute
+)
+
+// Dispatch is how the dispatches are retried and failed over.
+type Dispatch struct {
+	Backoff             backoff.Backoff
+	FailoverGracePeriod time.Duration
+}
+
+// DefaultDispatch returns the configuration used when the ConfigMap does not
+// configure it.
+func DefaultDispatch() *Dispatch {
+	return &Dispatch{
+		Backoff:             backoff.Default(),
+		FailoverGracePeriod: DefaultFailoverGracePeriod,
+	}
+}
+
+// NewDispatchFromConfigMap returns the configuration of the ConfigMap, the
+// keys not set keep their default.
+func NewDispatchFromConfigMap(cm *corev1.ConfigMap) (*Dispatch, error) {
+	b, err := backoff.NewFromConfigMap(cm)
+	if err != nil {
+		return nil, err
+	}
+	d := DefaultDispatch()
+	d.Backoff = b
+	if v, ok := cm.Data[FailoverGracePeriodKey]; ok {
+		period, err := time.ParseDuration(v)
+		if err != nil || period <= 0 {
+			return nil, fmt.Errorf("invalid %s %q: must be a positive duration", FailoverGracePeriodKey, v)
+		}
+		d.FailoverGracePeriod = period
+	}
+	return d, nil
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/openshift-pipelines/tekton-armadas/pkg/backoff"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestNewDispatchFromConfigMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    map[string]string
+		want    *Dispatch
+		wantErr bool
+	}{
+		{
+			name: "defaults",
+			want: DefaultDispatch(),
+		},
+		{
+			name: "all keys",
+			data: map[string]string{
+				backoff.InitialDelayKey: "1s",
+				backoff.MaxDelayKey:     "1m",
+				backoff.MaxAttemptsKey:  "0",
+				FailoverGracePeriodKey:  "10m",
+			},
+			want: &Dispatch{
+				Backoff: backoff.Backoff{
+					InitialDelay: time.Second,
+					MaxDelay:     time.Minute,
+					MaxAttempts:  0,
+				},
+				FailoverGracePeriod: 10 * time.Minute,
+			},
+		},
+		{
+			name:    "bad failover grace period",
+			data:    map[string]string{FailoverGracePeriodKey: "five minutes"},
+			wantErr: true,
+		},
+		{
+			name:    "zero failover grace period",
+			data:    map[string]string{FailoverGracePeriodKey: "0s"},
+			wantErr: true,
+		},
+		{
+			name:    "bad initial delay",
+			data:    map[string]string{backoff.InitialDelayKey: "soon"},
+			wantErr: true,
+		},
+		{
+			name:    "bad max delay",
+			data:    map[string]string{backoff.MaxDelayKey: "-1m"},
+			wantErr: true,
+		},
+		{
+			name:    "bad max attempts",
+			data:    map[string]string{backoff.MaxAttemptsKey: "-1"},
+			wantErr: true,
+		},
+		{
+			name: "max delay lower than initial delay",
+			data: map[string]string{
+				backoff.InitialDelayKey: "1m",
+				backoff.MaxDelayKey:     "1s",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewDispatchFromConfigMap(&corev1.ConfigMap{Data: tt.data})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewDispatchFromConfigMap() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewDispatchFromConfigMap() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
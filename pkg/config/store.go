@@ -0,0 +1,102 @@
+package config
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/configmap"
+	"knative.dev/pkg/system"
+)
+
+type cfgKey struct{}
+
+// Config holds the configuration of the orchestrator.
+type Config struct {
+	Armada   *Armada
+	Dispatch *Dispatch
+}
+
+// FromContext returns the configuration attached to the context, nil when
+// there is none.
+func FromContext(ctx context.Context) *Config {
+	x, ok := ctx.Value(cfgKey{}).(*Config)
+	if ok {
+		return x
+	}
+	return nil
+}
+
+// FromContextOrDefaults returns the configuration attached to the context,
+// the default one for what is missing.
+func FromContextOrDefaults(ctx context.Context) *Config {
+	cfg := FromContext(ctx)
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	if cfg.Armada == nil {
+		cfg.Armada = DefaultArmada()
+	}
+	if cfg.Dispatch == nil {
+		cfg.Dispatch = DefaultDispatch()
+	}
+	return cfg
+}
+
+// ToContext attaches the configuration to the context.
+func ToContext(ctx context.Context, c *Config) context.Context {
+	return context.WithValue(ctx, cfgKey{}, c)
+}
+
+// Store is a typed wrapper around configmap.UntypedStore to handle the
+// ConfigMaps of the orchestrator.
+type Store struct {
+	*configmap.UntypedStore
+}
+
+// NewStore creates a configmap.UntypedStore based config store.
+func NewStore(logger configmap.Logger, onAfterStore ...func(name string, value interface{})) *Store {
+	return &Store{
+		UntypedStore: configmap.NewUntypedStore(
+			"armada",
+			logger,
+			configmap.Constructors{
+				ArmadaConfigName:   NewArmadaFromConfigMap,
+				DispatchConfigName: NewDispatchFromConfigMap,
+			},
+			onAfterStore...,
+		),
+	}
+}
+
+// WatchConfigs watches the ConfigMaps of the store, with their defaults when
+// they do not exist and the watcher supports it.
+func (s *Store) WatchConfigs(w configmap.Watcher) {
+	dw, ok := w.(configmap.DefaultingWatcher)
+	if !ok {
+		s.UntypedStore.WatchConfigs(w)
+		return
+	}
+	for _, name := range []string{ArmadaConfigName, DispatchConfigName} {
+		dw.WatchWithDefault(corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: system.Namespace()},
+		}, s.OnConfigChanged)
+	}
+}
+
+// ToContext attaches the current configuration to the context.
+func (s *Store) ToContext(ctx context.Context) context.Context {
+	return ToContext(ctx, s.Load())
+}
+
+// Load returns the current configuration.
+func (s *Store) Load() *Config {
+	cfg := &Config{}
+	if armada, ok := s.UntypedLoad(ArmadaConfigName).(*Armada); ok {
+		cfg.Armada = armada
+	}
+	if dispatch, ok := s.UntypedLoad(DispatchConfigName).(*Dispatch); ok {
+		cfg.Dispatch = dispatch
+	}
+	return cfg
+}
@@ -0,0 +1,88 @@
+package minion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+
+	"github.com/openshift-pipelines/tekton-armadas/pkg/apis/armada"
+	"github.com/openshift-pipelines/tekton-armadas/pkg/types"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// resourceClient is the subset of the typed clients needed to apply a resource.
+type resourceClient[T metav1.Object] interface {
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (T, error)
+	Create(ctx context.Context, obj T, opts metav1.CreateOptions) (T, error)
+	Update(ctx context.Context, obj T, opts metav1.UpdateOptions) (T, error)
+}
+
+// errUnmanagedResource is returned when a resource bundled with a run
+// already exists on the minion without having been applied by armada.
+var errUnmanagedResource = errors.New("resource not managed by armada")
+
+// isManaged checks if the resource has been applied by armada.
+func isManaged(obj metav1.Object) bool {
+	return obj.GetLabels()[armada.LabelManagedBy] == armada.ManagedBy
+}
+
+// setManaged labels the resource as applied by armada.
+func setManaged(obj metav1.Object) {
+	labels := maps.Clone(obj.GetLabels())
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[armada.LabelManagedBy] = armada.ManagedBy
+	obj.SetLabels(labels)
+}
+
+// apply creates the resource or updates it when it already exists, as long
+// as it has been applied by armada.
+func apply[T metav1.Object](ctx context.Context, client resourceClient[T], obj T) error {
+	setManaged(obj)
+	existing, err := client.Get(ctx, obj.GetName(), metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		_, err = client.Create(ctx, obj, metav1.CreateOptions{})
+		return err
+	case err != nil:
+		return err
+	case !isManaged(existing):
+		return fmt.Errorf("%w: %s already exists without the %s=%s label", errUnmanagedResource, obj.GetName(), armada.LabelManagedBy, armada.ManagedBy)
+	}
+	obj.SetResourceVersion(existing.GetResourceVersion())
+	_, err = client.Update(ctx, obj, metav1.UpdateOptions{})
+	return err
+}
+
+// applyResources applies the resources bundled with the run, in the order
+// they depend on each other: ConfigMaps and Secrets, Tasks then Pipelines.
+func (c *controller) applyResources(ctx context.Context, namespace string, tt types.Types) error {
+	for _, cm := range tt.Kube.ConfigMaps {
+		if err := apply(ctx, c.clients.Kube.CoreV1().ConfigMaps(namespace), cm); err != nil {
+			return fmt.Errorf("error applying configmap %s: %w", cm.GetName(), err)
+		}
+		c.logger.Infof("configmap %s has been applied", cm.GetName())
+	}
+	for _, secret := range tt.Kube.Secrets {
+		if err := apply(ctx, c.clients.Kube.CoreV1().Secrets(namespace), secret); err != nil {
+			return fmt.Errorf("error applying secret %s: %w", secret.GetName(), err)
+		}
+		c.logger.Infof("secret %s has been applied", secret.GetName())
+	}
+	for _, task := range tt.Tekton.Tasks {
+		if err := apply(ctx, c.clients.Tekton.TektonV1().Tasks(namespace), task); err != nil {
+			return fmt.Errorf("error applying task %s: %w", task.GetName(), err)
+		}
+		c.logger.Infof("task %s has been applied", task.GetName())
+	}
+	for _, pipeline := range tt.Tekton.Pipelines {
+		if err := apply(ctx, c.clients.Tekton.TektonV1().Pipelines(namespace), pipeline); err != nil {
+			return fmt.Errorf("error applying pipeline %s: %w", pipeline.GetName(), err)
+		}
+		c.logger.Infof("pipeline %s has been applied", pipeline.GetName())
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package minion
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// DefaultConfigName is the name of the ConfigMap configuring the minion
+	// in its namespace when ARMADA_CONFIG_NAME is not set.
+	DefaultConfigName = "config-minion"
+
+	namespaceMappingKey        = "namespace-mapping"
+	namespacePrefixKey         = "namespace-prefix"
+	namespaceSuffixKey         = "namespace-suffix"
+	namespaceMapKey            = "namespace-map"
+	namespaceAutoCreateKey     = "namespace-auto-create"
+	namespaceLabelsKey         = "namespace-labels"
+	namespaceServiceAccountKey = "namespace-service-account"
+	namespaceDenyListKey       = "namespace-deny-list"
+	// defaultNamespaceDenyList are always denied, the deny list adds to them.
+	defaultNamespaceDenyList = "kube-system,kube-public,kube-node-lease"
+
+	policyAllowedRegistriesKey       = "policy-allowed-registries"
+	policyAllowHostNetworkKey        = "policy-allow-host-network"
+	policyAllowPrivilegedKey         = "policy-allow-privileged"
+	policyMaxTimeoutKey              = "policy-max-timeout"
+	policyAllowedServiceAccountsKey  = "policy-allowed-service-accounts"
+	policyAllowedSourceNamespacesKey = "policy-allowed-source-namespaces"
+
+	serviceAccountCreateKey      = "service-account-create"
+	serviceAccountTemplateKey    = "service-account-template"
+	serviceAccountClusterRoleKey = "service-account-cluster-role"
+
+	namespaceMappingIdentity = "identity"
+	namespaceMappingPrefix   = "prefix"
+	namespaceMappingStatic   = "static"
+	namespaceMappingTenant   = "tenant"
+)
+
+// minionConfig is the configuration of the minion read from its ConfigMap.
+type minionConfig struct {
+	// NamespaceMapping is how the namespace of the source run is mapped to
+	// the namespace the run is created in: identity, prefix (with the prefix
+	// and suffix), static (with the map) or tenant (the prefix and suffix
+	// around the tenant label of the run).
+	NamespaceMapping string
+	NamespacePrefix  string
+	NamespaceSuffix  string
+	NamespaceMap     map[string]string
+
+	// AutoCreateNamespace creates the missing target namespaces with the
+	// labels and the ServiceAccount when set.
+	AutoCreateNamespace     bool
+	NamespaceLabels         map[string]string
+	NamespaceServiceAccount string
+
+	// DenyNamespaces are the target namespaces the runs are never created
+	// in, the default ones and the ones of the deny list.
+	DenyNamespaces map[string]bool
+
+	// CreateServiceAccounts creates the missing ServiceAccounts of the runs
+	// out of ServiceAccountTemplate, bound to ServiceAccountClusterRole when
+	// set. The runs with a missing ServiceAccount fail otherwise.
+	CreateServiceAccounts     bool
+	ServiceAccountTemplate    *corev1.ServiceAccount
+	ServiceAccountClusterRole string
+
+	// Policy is what the runs are allowed to do.
+	Policy policy
+}
+
+func defaultMinionConfig() *minionConfig {
+	cfg, _ := newMinionConfigFromConfigMap(&corev1.ConfigMap{})
+	return cfg
+}
+
+// splitList returns the non empty items of the comma separated list.
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// newMinionConfigFromConfigMap returns the configuration of the ConfigMap, the
+// keys not set keep their default.
+func newMinionConfigFromConfigMap(cm *corev1.ConfigMap) (*minionConfig, error) {
+	cfg := &minionConfig{
+		NamespaceMapping: namespaceMappingIdentity,
+		NamespacePrefix:  cm.Data[namespacePrefixKey],
+		NamespaceSuffix:  cm.Data[namespaceSuffixKey],
+		NamespaceMap:     map[string]string{},
+		NamespaceLabels:  map[string]string{},
+		DenyNamespaces:   map[string]bool{},
+	}
+
+	if v, ok := cm.Data[namespaceMappingKey]; ok && v != "" {
+		switch v {
+		case namespaceMappingIdentity, namespaceMappingPrefix, namespaceMappingStatic, namespaceMappingTenant:
+			cfg.NamespaceMapping = v
+		default:
+			return nil, fmt.Errorf("invalid %s %q", namespaceMappingKey, v)
+		}
+	}
+	if v, ok := cm.Data[namespaceMapKey]; ok {
+		if err := yaml.Unmarshal([]byte(v), &cfg.NamespaceMap); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", namespaceMapKey, err)
+		}
+	}
+	for key, value := range map[string]*bool{
+		namespaceAutoCreateKey:    &cfg.AutoCreateNamespace,
+		serviceAccountCreateKey:   &cfg.CreateServiceAccounts,
+		policyAllowHostNetworkKey: &cfg.Policy.AllowHostNetwork,
+		policyAllowPrivilegedKey:  &cfg.Policy.AllowPrivileged,
+	} {
+		v, ok := cm.Data[key]
+		if !ok {
+			continue
+		}
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %w", key, v, err)
+		}
+		*value = b
+	}
+	if v, ok := cm.Data[namespaceLabelsKey]; ok {
+		if err := yaml.Unmarshal([]byte(v), &cfg.NamespaceLabels); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", namespaceLabelsKey, err)
+		}
+	}
+	cfg.NamespaceServiceAccount = strings.TrimSpace(cm.Data[namespaceServiceAccountKey])
+	cfg.ServiceAccountTemplate = &corev1.ServiceAccount{}
+	if v, ok := cm.Data[serviceAccountTemplateKey]; ok {
+		if err := yaml.Unmarshal([]byte(v), cfg.ServiceAccountTemplate); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", serviceAccountTemplateKey, err)
+		}
+	}
+	cfg.ServiceAccountClusterRole = strings.TrimSpace(cm.Data[serviceAccountClusterRoleKey])
+
+	for _, ns := range splitList(defaultNamespaceDenyList + "," + cm.Data[namespaceDenyListKey]) {
+		cfg.DenyNamespaces[ns] = true
+	}
+
+	cfg.Policy.AllowedRegistries = splitList(cm.Data[policyAllowedRegistriesKey])
+	cfg.Policy.AllowedServiceAccounts = splitList(cm.Data[policyAllowedServiceAccountsKey])
+	cfg.Policy.AllowedSourceNamespaces = splitList(cm.Data[policyAllowedSourceNamespacesKey])
+	if v, ok := cm.Data[policyMaxTimeoutKey]; ok && v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %w", policyMaxTimeoutKey, v, err)
+		}
+		cfg.Policy.MaxTimeout = timeout
+	}
+	return cfg, nil
+}
+
+// getConfig returns the configuration of the minion, the default one when
+// its ConfigMap does not exist. The namespace of the minion, holding its
+// credentials and its configuration, and the namespace of Tekton Pipelines
+// are always denied whatever the deny list.
+func (c *controller) getConfig() (*minionConfig, error) {
+	cm, err := c.configMapLister.ConfigMaps(c.namespace).Get(c.configName)
+	var cfg *minionConfig
+	switch {
+	case errors.IsNotFound(err):
+		cfg = defaultMinionConfig()
+	case err != nil:
+		return nil, fmt.Errorf("failed to get configmap %s/%s: %w", c.namespace, c.configName, err)
+	default:
+		cfg, err = newMinionConfigFromConfigMap(cm)
+		if err != nil {
+			return nil, fmt.Errorf("invalid configmap %s/%s: %w", c.namespace, c.configName, err)
+		}
+	}
+	cfg.DenyNamespaces[c.namespace] = true
+	if c.tektonNamespace != "" {
+		cfg.DenyNamespaces[c.tektonNamespace] = true
+	}
+	return cfg, nil
+}
@@ -0,0 +1,152 @@
+package minion
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/openshift-pipelines/tekton-armadas/pkg/apis/armada"
+	"github.com/openshift-pipelines/tekton-armadas/pkg/types"
+	pipelineapi "github.com/tektoncd/pipeline/pkg/apis/pipeline"
+	tektonv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ktypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// dispatchedSelector selects the runs created for the dispatch with the
+// idempotency key. The TaskRuns of the dispatched PipelineRuns inherit its
+// labels, they are left out when looking for a TaskRun.
+func dispatchedSelector(key string, taskRun bool) string {
+	selector := armada.LabelIdempotencyKey + "=" + key
+	if taskRun {
+		selector += ",!" + pipelineapi.PipelineRunLabelKey
+	}
+	return selector
+}
+
+// findDispatched returns the PipelineRun created for the dispatch of the
+// source PipelineRun with the idempotency key, nil if there is none. The
+// PipelineRun is looked up in all the namespaces as the source namespace may
+// have been mapped to another one.
+func (c *controller) findDispatched(ctx context.Context, key string) (*tektonv1.PipelineRun, error) {
+	prs, err := c.clients.Tekton.TektonV1().PipelineRuns(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		LabelSelector: dispatchedSelector(key, false),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dispatched pipelineruns: %w", err)
+	}
+	if len(prs.Items) == 0 {
+		return nil, nil
+	}
+	return &prs.Items[0], nil
+}
+
+// findDispatchedTaskRun returns the TaskRun created for the dispatch of the
+// source TaskRun with the idempotency key, nil if there is none.
+func (c *controller) findDispatchedTaskRun(ctx context.Context, key string) (*tektonv1.TaskRun, error) {
+	trs, err := c.clients.Tekton.TektonV1().TaskRuns(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		LabelSelector: dispatchedSelector(key, true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dispatched taskruns: %w", err)
+	}
+	if len(trs.Items) == 0 {
+		return nil, nil
+	}
+	return &trs.Items[0], nil
+}
+
+// specStatusPatch sets the spec.status of a run.
+func specStatusPatch(specStatus string) ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"spec": map[string]any{"status": specStatus},
+	})
+}
+
+// doControl cancels or deletes the run created for the source run, a run
+// already gone is not an error.
+func (c *controller) doControl(ctx context.Context, eventType string, cEvent types.ArmadaControlEvent) error {
+	if errs := validation.IsValidLabelValue(cEvent.IdempotencyKey); cEvent.IdempotencyKey == "" || len(errs) > 0 {
+		return fmt.Errorf("%w: invalid idempotency key %q in control event", errInvalidEvent, cEvent.IdempotencyKey)
+	}
+	if cEvent.Kind == pipelineapi.TaskRunControllerName {
+		return c.doTaskRunControl(ctx, eventType, cEvent)
+	}
+
+	pr, err := c.findDispatched(ctx, cEvent.IdempotencyKey)
+	if err != nil {
+		return err
+	}
+	if pr == nil {
+		c.logger.Infof("no pipelinerun found for %s/%s, nothing to do for %s", cEvent.Namespace, cEvent.Name, eventType)
+		return nil
+	}
+
+	if eventType == types.EventTypeDelete {
+		if err := c.clients.Tekton.TektonV1().PipelineRuns(pr.GetNamespace()).Delete(ctx, pr.GetName(), metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("error deleting pipelinerun %s: %w", pr.GetName(), err)
+		}
+		c.logger.Infof("pipelinerun %s has been deleted", pr.GetName())
+		return nil
+	}
+
+	specStatus := tektonv1.PipelineRunSpecStatus(cEvent.SpecStatus)
+	if specStatus != tektonv1.PipelineRunSpecStatusCancelled &&
+		specStatus != tektonv1.PipelineRunSpecStatusCancelledRunFinally &&
+		specStatus != tektonv1.PipelineRunSpecStatusStoppedRunFinally {
+		return fmt.Errorf("invalid spec status %q to cancel pipelinerun %s", cEvent.SpecStatus, pr.GetName())
+	}
+	if pr.Spec.Status == specStatus || pr.IsDone() {
+		return nil
+	}
+
+	patch, err := specStatusPatch(cEvent.SpecStatus)
+	if err != nil {
+		return err
+	}
+	if _, err := c.clients.Tekton.TektonV1().PipelineRuns(pr.GetNamespace()).Patch(ctx, pr.GetName(), ktypes.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("error cancelling pipelinerun %s: %w", pr.GetName(), err)
+	}
+	c.logger.Infof("pipelinerun %s has been set to %s", pr.GetName(), cEvent.SpecStatus)
+	return nil
+}
+
+// doTaskRunControl cancels or deletes the TaskRun created for the source
+// TaskRun, a TaskRun already gone is not an error.
+func (c *controller) doTaskRunControl(ctx context.Context, eventType string, cEvent types.ArmadaControlEvent) error {
+	tr, err := c.findDispatchedTaskRun(ctx, cEvent.IdempotencyKey)
+	if err != nil {
+		return err
+	}
+	if tr == nil {
+		c.logger.Infof("no taskrun found for %s/%s, nothing to do for %s", cEvent.Namespace, cEvent.Name, eventType)
+		return nil
+	}
+
+	if eventType == types.EventTypeDelete {
+		if err := c.clients.Tekton.TektonV1().TaskRuns(tr.GetNamespace()).Delete(ctx, tr.GetName(), metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("error deleting taskrun %s: %w", tr.GetName(), err)
+		}
+		c.logger.Infof("taskrun %s has been deleted", tr.GetName())
+		return nil
+	}
+
+	if cEvent.SpecStatus != tektonv1.TaskRunSpecStatusCancelled {
+		return fmt.Errorf("invalid spec status %q to cancel taskrun %s", cEvent.SpecStatus, tr.GetName())
+	}
+	if tr.IsCancelled() || tr.IsDone() {
+		return nil
+	}
+
+	patch, err := specStatusPatch(cEvent.SpecStatus)
+	if err != nil {
+		return err
+	}
+	if _, err := c.clients.Tekton.TektonV1().TaskRuns(tr.GetNamespace()).Patch(ctx, tr.GetName(), ktypes.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("error cancelling taskrun %s: %w", tr.GetName(), err)
+	}
+	c.logger.Infof("taskrun %s has been set to %s", tr.GetName(), cEvent.SpecStatus)
+	return nil
+}
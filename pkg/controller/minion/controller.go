@@ -3,40 +3,148 @@ package minion
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"net/http"
 	"os"
 	"time"
 
 	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/openshift-pipelines/tekton-armadas/pkg/apis/armada"
+	"github.com/openshift-pipelines/tekton-armadas/pkg/apis/armada/v1alpha1"
 	"github.com/openshift-pipelines/tekton-armadas/pkg/clients"
+	"github.com/openshift-pipelines/tekton-armadas/pkg/payload"
+	"github.com/openshift-pipelines/tekton-armadas/pkg/signature"
 	"github.com/openshift-pipelines/tekton-armadas/pkg/types"
+	tektonv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
 	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
 	"knative.dev/eventing/pkg/adapter/v2"
 	"knative.dev/pkg/logging"
 	"knative.dev/pkg/system"
 )
 
+var errInvalidEvent = errors.New("invalid event")
+
+// ReasonUnsupportedSchemaVersion is the reason of the rejection of the
+// events of a major version the minion does not know.
+const ReasonUnsupportedSchemaVersion = "UnsupportedSchemaVersion"
+
 const (
-	globalControllerPort = "8081"
-	httpTimeoutHandler   = 600 * time.Second
+	globalControllerPort     = "8081"
+	httpTimeoutHandler       = 600 * time.Second
+	defaultHeartbeatInterval = 30 * time.Second
+	defaultTektonNamespace   = "tekton-pipelines"
+	defaultMaxPayloadSize    = 32 * 1024 * 1024
+	// chunkTTL is how long the chunks of an event are kept waiting for the
+	// others.
+	chunkTTL = 5 * time.Minute
+	// chunkedEvents is the number of events of the largest size whose chunks
+	// can be waiting at once.
+	chunkedEvents = 4
 )
 
 // controller generates events at a regular interval.
 type controller struct {
-	logger   *zap.SugaredLogger
-	clients  *clients.Clients
+	logger  *zap.SugaredLogger
+	clients *clients.Clients
+	// interval is how often the heartbeats are sent to the orchestrator.
 	interval time.Duration
+	// tektonNamespace is where Tekton Pipelines is installed.
+	tektonNamespace string
+	// maxPayloadSize is the size of the largest event accepted, received,
+	// reassembled or decompressed.
+	maxPayloadSize int64
+	// assembler reassembles the events sent in chunks.
+	assembler *payload.Assembler
+	// ceClient sends events to the orchestrator at sink.
+	ceClient cloudevents.Client
+	sink     string
+
+	namespace       string
+	secretLister    corev1listers.SecretLister
+	configMapLister corev1listers.ConfigMapLister
+	// configName is the ConfigMap in the minion namespace configuring the
+	// mapping of the namespaces.
+	configName string
+	// signatureSecret is the Secret holding the key the events of the
+	// orchestrator are signed with.
+	signatureSecret string
+	// insecureUnsigned accepts the unsigned events when there is no
+	// signatureSecret, for development only.
+	insecureUnsigned bool
+	// encryptionSecret is the Secret holding the private key the Secrets
+	// sent by the orchestrator are encrypted for.
+	encryptionSecret string
+	// tlsSecret and tlsClientCASecret enable TLS and the verification of
+	// the client certificates when set.
+	tlsSecret         string
+	tlsClientCASecret string
+
+	// mode is pull when the minion polls the orchestrator for its events as
+	// the Minion minionName instead of serving them.
+	mode       v1alpha1.MinionMode
+	minionName string
 }
 
+// envConfig is the configuration of the minion, K_SINK is the orchestrator
+// URL where the status of the PipelineRuns is reported.
 type envConfig struct {
 	adapter.EnvConfig
+
+	// SignatureSecret is the name of the Secret in the minion namespace
+	// holding the key shared with the orchestrator to sign the events.
+	SignatureSecret string `envconfig:"ARMADA_SIGNATURE_SECRET"`
+
+	// InsecureUnsigned accepts the unsigned events when SignatureSecret is
+	// not set, anyone reaching the minion can then create runs on it.
+	InsecureUnsigned bool `envconfig:"ARMADA_INSECURE_UNSIGNED" default:"false"`
+
+	// EncryptionSecret is the name of the Secret in the minion namespace
+	// holding the private-key.pem the Secrets sent with the runs are
+	// encrypted for, the orchestrator has its public key.
+	EncryptionSecret string `envconfig:"ARMADA_ENCRYPTION_SECRET"`
+
+	// TLSSecret is the name of the TLS Secret in the minion namespace with
+	// the certificate served by the minion.
+	TLSSecret string `envconfig:"ARMADA_TLS_SECRET"`
+
+	// TLSClientCASecret is the name of the Secret in the minion namespace
+	// with the ca.crt the orchestrator client certificates are verified against.
+	TLSClientCASecret string `envconfig:"ARMADA_TLS_CLIENT_CA_SECRET"`
+
+	// Mode is pull to poll the orchestrator for the events instead of
+	// serving them, for the minions the orchestrator cannot reach.
+	Mode string `envconfig:"ARMADA_MODE" default:"push"`
+
+	// MinionName is the name of the Minion on the orchestrator, required in
+	// pull mode.
+	MinionName string `envconfig:"ARMADA_MINION_NAME"`
+
+	// ConfigName is the name of the ConfigMap in the minion namespace
+	// configuring how the namespaces of the runs are mapped.
+	ConfigName string `envconfig:"ARMADA_CONFIG_NAME" default:"config-minion"`
+
+	// HeartbeatInterval is how often the minion reports its health to the
+	// orchestrator, the heartbeats need MinionName.
+	HeartbeatInterval time.Duration `envconfig:"ARMADA_HEARTBEAT_INTERVAL" default:"30s"`
+
+	// TektonNamespace is the namespace where Tekton Pipelines is installed,
+	// to report its version.
+	TektonNamespace string `envconfig:"ARMADA_TEKTON_NAMESPACE" default:"tekton-pipelines"`
+
+	// MaxPayloadSize is the size of the largest event accepted once
+	// reassembled and decompressed, as a quantity such as 32Mi.
+	MaxPayloadSize string `envconfig:"ARMADA_MAX_PAYLOAD_SIZE" default:"32Mi"`
 }
 
 func NewEnvConfig() adapter.EnvConfigAccessor {
 	return &envConfig{
-		adapter.EnvConfig{
+		EnvConfig: adapter.EnvConfig{
 			Namespace: system.Namespace(),
 		},
 	}
@@ -45,41 +153,173 @@ func NewEnvConfig() adapter.EnvConfigAccessor {
 type Response struct {
 	Status  int    `json:"status"`
 	Message string `json:"message"`
+	// Reason is the machine readable reason of a rejection by the policy.
+	Reason string `json:"reason,omitempty"`
 }
 
 func (c *controller) writeResponse(response http.ResponseWriter, statusCode int, message string) {
-	response.WriteHeader(statusCode)
+	c.writeBody(response, Response{Status: statusCode, Message: message})
+}
+
+func (c *controller) writeBody(response http.ResponseWriter, body Response) {
 	response.Header().Set("Content-Type", "application/json")
-	body := Response{
-		Status:  statusCode,
-		Message: message,
-	}
+	response.WriteHeader(body.Status)
 	if err := json.NewEncoder(response).Encode(body); err != nil {
 		c.logger.Errorf("failed to write back sink response: %v", err)
 	}
 }
 
-func (c *controller) doTypes(ctx context.Context, aEvent types.ArmadaEvent) error {
-	tt, err := types.ReadTektonTypes(ctx, []string{aEvent.PipelineRun})
+// readEvent decodes the runs of the event with the resources bundled with them.
+func readEvent(ctx context.Context, aEvent types.ArmadaEvent) (types.Types, error) {
+	tt, err := types.ReadTektonTypes(ctx, aEvent.YAMLs())
 	if err != nil {
-		return fmt.Errorf("failed to read tekton types: %w", err)
+		return types.Types{}, fmt.Errorf("failed to read tekton types: %w", err)
+	}
+	return tt, nil
+}
+
+func (c *controller) doTypes(ctx context.Context, cfg *minionConfig, aEvent types.ArmadaEvent, tt types.Types, secrets []*corev1.Secret) error {
+	// an event carries a single run, its labels select the tenant
+	var runLabels map[string]string
+	switch {
+	case len(tt.Tekton.PipelineRuns) > 0:
+		runLabels = tt.Tekton.PipelineRuns[0].GetLabels()
+	case len(tt.Tekton.TaskRuns) > 0:
+		runLabels = tt.Tekton.TaskRuns[0].GetLabels()
+	}
+	if aEvent.Options != nil && aEvent.Options.Tenant != "" {
+		runLabels = maps.Clone(runLabels)
+		if runLabels == nil {
+			runLabels = map[string]string{}
+		}
+		runLabels[armada.LabelTenant] = aEvent.Options.Tenant
+	}
+	namespace, err := c.prepareNamespace(ctx, cfg, aEvent.Namespace, runLabels)
+	if err != nil {
+		return err
+	}
+
+	// a missing serviceaccount is reported before anything is applied
+	if err := c.ensureServiceAccounts(ctx, cfg, namespace, tt, secrets); err != nil {
+		return err
 	}
 
+	if err := c.applyResources(ctx, namespace, tt); err != nil {
+		return err
+	}
+	// the secrets are there before the run starts, owned by the run once
+	// it has been created
+	if err := c.applySecrets(ctx, namespace, secrets, nil); err != nil {
+		return err
+	}
+
+	prClient := c.clients.Tekton.TektonV1().PipelineRuns(namespace)
 	for _, pr := range tt.Tekton.PipelineRuns {
-		if _, err := c.clients.Tekton.TektonV1().PipelineRuns(aEvent.Namespace).Get(ctx, pr.GetName(), metav1.GetOptions{}); err == nil {
-			if err := c.clients.Tekton.TektonV1().PipelineRuns(aEvent.Namespace).Delete(ctx, pr.GetName(), metav1.DeleteOptions{}); err != nil {
-				return fmt.Errorf("error deleting pipelinerun %s: %w", pr.GetName(), err)
+		setSource(pr, aEvent)
+		name, created, err := createRun(ctx, prClient, func(ctx context.Context, selector string) (string, error) {
+			prs, err := prClient.List(ctx, metav1.ListOptions{LabelSelector: selector})
+			if err != nil || len(prs.Items) == 0 {
+				return "", err
+			}
+			return prs.Items[0].GetName(), nil
+		}, pr, aEvent.IdempotencyKey)
+		if err != nil {
+			return fmt.Errorf("error creating pipelinerun: %w", err)
+		}
+		if len(secrets) > 0 {
+			owner, err := runOwner(ctx, prClient, name, tektonv1.SchemeGroupVersion.WithKind("PipelineRun"))
+			if err != nil {
+				return fmt.Errorf("error getting pipelinerun %s: %w", name, err)
 			}
-			c.logger.Info(fmt.Sprintf("pipelinerun %s has been deleted", pr.GetName()))
+			if err := c.applySecrets(ctx, namespace, secrets, &owner); err != nil {
+				return err
+			}
+		}
+		if !created {
+			c.logger.Infof("pipelinerun %s has already been created for %s/%s", name, aEvent.Namespace, aEvent.Name)
+			continue
 		}
+		c.logger.Infof("pipelinerun %s has been created", name)
+	}
 
-		cp, err := c.clients.Tekton.TektonV1().PipelineRuns(aEvent.Namespace).Create(ctx, pr, metav1.CreateOptions{})
+	trClient := c.clients.Tekton.TektonV1().TaskRuns(namespace)
+	for _, tr := range tt.Tekton.TaskRuns {
+		setSource(tr, aEvent)
+		name, created, err := createRun(ctx, trClient, func(ctx context.Context, selector string) (string, error) {
+			trs, err := trClient.List(ctx, metav1.ListOptions{LabelSelector: selector})
+			if err != nil || len(trs.Items) == 0 {
+				return "", err
+			}
+			return trs.Items[0].GetName(), nil
+		}, tr, aEvent.IdempotencyKey)
 		if err != nil {
-			return fmt.Errorf("error creating pipelinerun: %w", err)
+			return fmt.Errorf("error creating taskrun: %w", err)
 		}
-		c.logger.Info(fmt.Sprintf("pipelinerun %s has been created", cp.GetName()))
+		if len(secrets) > 0 {
+			owner, err := runOwner(ctx, trClient, name, tektonv1.SchemeGroupVersion.WithKind("TaskRun"))
+			if err != nil {
+				return fmt.Errorf("error getting taskrun %s: %w", name, err)
+			}
+			if err := c.applySecrets(ctx, namespace, secrets, &owner); err != nil {
+				return err
+			}
+		}
+		if !created {
+			c.logger.Infof("taskrun %s has already been created for %s/%s", name, aEvent.Namespace, aEvent.Name)
+			continue
+		}
+		c.logger.Infof("taskrun %s has been created", name)
+	}
+	return nil
+}
+
+// setSource stamps on the run where it comes from so its status can be
+// reported back to the orchestrator.
+func setSource(run metav1.Object, aEvent types.ArmadaEvent) {
+	labels := run.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[armada.LabelDispatched] = "true"
+	if aEvent.IdempotencyKey != "" {
+		labels[armada.LabelIdempotencyKey] = aEvent.IdempotencyKey
+	}
+	run.SetLabels(labels)
+
+	annotations := run.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[armada.AnnotationSourceNamespace] = aEvent.Namespace
+	annotations[armada.AnnotationSourceName] = aEvent.Name
+	run.SetAnnotations(annotations)
+}
+
+// verifySignature checks the request has been signed by the orchestrator
+// with the shared secret.
+func (c *controller) verifySignature(request *http.Request) error {
+	secret, err := c.getSecretKey(c.signatureSecret, signature.SecretKey)
+	if err != nil {
+		return err
+	}
+	return signature.VerifyRequest(secret, request, signature.DefaultMaxSkew)
+}
+
+// verifiesSignatures checks if the requests of the orchestrator must be
+// signed, they always are unless the minion has been explicitly started
+// without a signature secret.
+func (c *controller) verifiesSignatures() bool {
+	return c.signatureSecret != "" || !c.insecureUnsigned
+}
+
+// verifyEventSignature checks the event has been signed by the orchestrator
+// with the shared secret, for the events delivered by a broker.
+func (c *controller) verifyEventSignature(event cloudevents.Event) error {
+	secret, err := c.getSecretKey(c.signatureSecret, signature.SecretKey)
+	if err != nil {
+		return err
 	}
-	return err
+	return signature.VerifyEvent(secret, event, signature.DefaultMaxSkew)
 }
 
 func (c *controller) handleEvent(ctx context.Context) http.HandlerFunc {
@@ -89,27 +329,174 @@ func (c *controller) handleEvent(ctx context.Context) http.HandlerFunc {
 			return
 		}
 
+		// the events sent through a broker are signed in their extension
+		// attributes as the broker does not keep the signature headers
+		request.Body = http.MaxBytesReader(response, request.Body, c.maxPayloadSize)
+		signedRequest := request.Header.Get(signature.HeaderSignature) != ""
+		if c.verifiesSignatures() && signedRequest {
+			if err := c.verifySignature(request); err != nil {
+				c.logger.Errorf("rejecting event: %v", err)
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					c.writeResponse(response, http.StatusRequestEntityTooLarge, err.Error())
+					return
+				}
+				c.writeResponse(response, http.StatusUnauthorized, err.Error())
+				return
+			}
+		}
+
 		event, err := cloudevents.NewEventFromHTTPRequest(request)
 		if err != nil {
 			c.logger.Errorf("failed to create event from request: %v", err)
-			response.WriteHeader(http.StatusInternalServerError)
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				c.writeResponse(response, http.StatusRequestEntityTooLarge, err.Error())
+				return
+			}
+			c.writeResponse(response, http.StatusBadRequest, "invalid cloudevent")
+			return
 		}
 		c.logger.Debugf("Received event: %s", event.String())
 
-		aEvent := types.ArmadaEvent{}
-		if err := event.DataAs(&aEvent); err != nil {
-			c.logger.Errorf("failed to convert event data: %v", err)
+		if c.verifiesSignatures() && !signedRequest {
+			if err := c.verifyEventSignature(*event); err != nil {
+				c.logger.Errorf("rejecting event %s: %v", event.ID(), err)
+				c.writeResponse(response, http.StatusUnauthorized, err.Error())
+				return
+			}
+		}
+
+		// the events over the chunk size of the orchestrator come in chunks,
+		// each of them signed, the event is processed with the last one
+		if event.Type() == types.EventTypeChunk {
+			assembled, err := c.assembler.Add(*event)
+			switch {
+			case errors.Is(err, types.ErrUnsupportedSchemaVersion):
+				c.writeBody(response, Response{Status: http.StatusBadRequest, Message: err.Error(), Reason: ReasonUnsupportedSchemaVersion})
+				return
+			case errors.Is(err, payload.ErrTooLarge):
+				c.logger.Errorf("rejecting chunk %s: %v", event.ID(), err)
+				c.writeResponse(response, http.StatusRequestEntityTooLarge, err.Error())
+				return
+			case err != nil:
+				c.logger.Errorf("rejecting chunk %s: %v", event.ID(), err)
+				c.writeResponse(response, http.StatusBadRequest, err.Error())
+				return
+			case assembled == nil:
+				c.writeResponse(response, http.StatusAccepted, fmt.Sprintf("chunk %s received", event.ID()))
+				return
+			}
+			c.logger.Debugf("reassembled event %s from its chunks", assembled.ID())
+			event = assembled
 		}
 
-		if err := c.doTypes(ctx, aEvent); err != nil {
-			c.logger.Errorf("failed to do types: %+v", err)
-			c.writeResponse(response, http.StatusInternalServerError, "failed to read tekton types")
+		if err := c.processEvent(ctx, *event); err != nil {
+			c.logger.Errorf("failed to process event %s: %+v", event.ID(), err)
+			if body, ok := rejection(err); ok {
+				c.writeBody(response, body)
+				return
+			}
+			c.writeResponse(response, http.StatusInternalServerError, err.Error())
 			return
 		}
 
 		// output a json message with details
-		c.writeResponse(response, http.StatusAccepted, fmt.Sprintf(`{"message": "created", "event": %s}`, event.Context.GetID()))
+		c.writeResponse(response, http.StatusAccepted, fmt.Sprintf(`{"message": "applied", "event": %s}`, event.Context.GetID()))
+	}
+}
+
+// rejection returns the response rejecting the event for the error of its
+// processing, false when the error does not come from the event and
+// processing it again may succeed.
+func rejection(err error) (Response, bool) {
+	var pv *policyViolation
+	switch {
+	case errors.As(err, &pv):
+		return Response{Status: http.StatusForbidden, Message: pv.Message, Reason: pv.Reason}, true
+	case errors.Is(err, types.ErrUnsupportedSchemaVersion):
+		return Response{Status: http.StatusBadRequest, Message: err.Error(), Reason: ReasonUnsupportedSchemaVersion}, true
+	case errors.Is(err, payload.ErrTooLarge):
+		return Response{Status: http.StatusRequestEntityTooLarge, Message: err.Error()}, true
+	case errors.Is(err, errServiceAccountNotFound):
+		return Response{Status: http.StatusUnprocessableEntity, Message: err.Error(), Reason: types.ReasonServiceAccountNotFound}, true
+	case errors.Is(err, errInvalidEvent):
+		return Response{Status: http.StatusBadRequest, Message: err.Error()}, true
+	case errors.Is(err, errForbiddenNamespace):
+		return Response{Status: http.StatusForbidden, Message: err.Error()}, true
+	case errors.Is(err, errUnmanagedResource):
+		return Response{Status: http.StatusConflict, Message: err.Error()}, true
+	}
+	return Response{}, false
+}
+
+// processEvent applies an event of the orchestrator, received or pulled.
+func (c *controller) processEvent(ctx context.Context, event cloudevents.Event) error {
+	if err := payload.Decompress(&event, c.maxPayloadSize); err != nil {
+		if errors.Is(err, payload.ErrTooLarge) {
+			return err
+		}
+		return fmt.Errorf("%w: %s", errInvalidEvent, err.Error())
+	}
+
+	switch event.Type() {
+	case types.EventTypeCancel, types.EventTypeDelete:
+		cEvent := types.ArmadaControlEvent{}
+		if err := event.DataAs(&cEvent); err != nil {
+			return fmt.Errorf("%w: invalid control event data: %s", errInvalidEvent, err.Error())
+		}
+		return c.doControl(ctx, event.Type(), cEvent)
+	case types.EventTypeDispatch:
+	default:
+		// the type is signed, it decides what the minion does with the event
+		return fmt.Errorf("%w: unexpected event type %s", errInvalidEvent, event.Type())
+	}
+
+	aEvent, err := c.decodeEvent(event)
+	if err != nil {
+		return err
+	}
+	tt, err := readEvent(ctx, aEvent)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errInvalidEvent, err.Error())
+	}
+	cfg, err := c.getConfig()
+	if err != nil {
+		return err
+	}
+	// the policy is evaluated before anything is created on the cluster
+	if err := cfg.Policy.admit(aEvent, tt); err != nil {
+		return err
+	}
+	// once the minion has a key, the secrets are only accepted encrypted
+	if c.encryptionSecret != "" && len(tt.Kube.Secrets) > 0 {
+		return fmt.Errorf("%w: the event has %d secrets in plaintext and the minion only accepts encrypted secrets", errInvalidEvent, len(tt.Kube.Secrets))
+	}
+	secrets, err := c.openSecrets(aEvent)
+	if err != nil {
+		return err
+	}
+	return c.doTypes(ctx, cfg, aEvent, tt, secrets)
+}
+
+// decodeEvent decodes the data of a dispatch event, rejecting the events of
+// an unknown major version, not matching the schema of their version or
+// dispatched to another minion.
+func (c *controller) decodeEvent(event cloudevents.Event) (types.ArmadaEvent, error) {
+	aEvent, err := types.DecodeArmadaEvent(event.Data())
+	switch {
+	case errors.Is(err, types.ErrUnsupportedSchemaVersion):
+		return types.ArmadaEvent{}, err
+	case err != nil:
+		return types.ArmadaEvent{}, fmt.Errorf("%w: %s", errInvalidEvent, err.Error())
+	}
+	if schema := event.DataSchema(); schema != "" && schema != types.DataSchema(aEvent.SchemaVersion) {
+		return types.ArmadaEvent{}, fmt.Errorf("%w: dataschema %s does not match the schema version %s", errInvalidEvent, schema, aEvent.SchemaVersion)
+	}
+	if aEvent.Minion != "" && c.minionName != "" && aEvent.Minion != c.minionName {
+		return types.ArmadaEvent{}, fmt.Errorf("%w: dispatched to minion %s, not %s", errInvalidEvent, aEvent.Minion, c.minionName)
 	}
+	return aEvent, nil
 }
 
 func (c *controller) Start(ctx context.Context) error {
@@ -119,6 +506,22 @@ func (c *controller) Start(ctx context.Context) error {
 		controllerPort = envControllerPort
 	}
 
+	if c.signatureSecret == "" {
+		if !c.insecureUnsigned {
+			return errors.New("ARMADA_SIGNATURE_SECRET is required, set ARMADA_INSECURE_UNSIGNED=true to accept the unsigned events")
+		}
+		c.logger.Warn("ARMADA_INSECURE_UNSIGNED is set, the events will not be authenticated and anyone reaching the minion can create runs on it")
+	}
+	c.startSecretInformer(ctx)
+
+	if err := c.startStatusReporter(ctx); err != nil {
+		return err
+	}
+
+	if c.mode == v1alpha1.MinionModePull {
+		return c.startPuller(ctx)
+	}
+
 	mux := http.NewServeMux()
 	mux.HandleFunc("/live", func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -127,21 +530,61 @@ func (c *controller) Start(ctx context.Context) error {
 
 	mux.HandleFunc("/", c.handleEvent(ctx))
 
+	// the logs are streamed, they are not subject to the timeout
+	root := http.NewServeMux()
+	root.Handle("POST /logs", c.handleLogs())
+	root.Handle("/", http.TimeoutHandler(mux, httpTimeoutHandler, "Listener Timeout!\n"))
+
 	//nolint: gosec
 	srv := &http.Server{
 		Addr:    ":" + controllerPort,
-		Handler: http.TimeoutHandler(mux, httpTimeoutHandler, "Listener Timeout!\n"),
+		Handler: root,
 	}
 
-	return srv.ListenAndServe()
+	if c.tlsSecret == "" {
+		return srv.ListenAndServe()
+	}
+	if c.tlsClientCASecret == "" {
+		c.logger.Warn("ARMADA_TLS_CLIENT_CA_SECRET is not set, the client certificates will not be verified")
+	}
+	loader := &tlsConfigLoader{c: c, certSecret: c.tlsSecret, clientCASecret: c.tlsClientCASecret}
+	srv.TLSConfig = loader.tlsConfig()
+	return srv.ListenAndServeTLS("", "")
 }
 
 func NewController(clients *clients.Clients) adapter.AdapterConstructor {
-	return func(ctx context.Context, _ adapter.EnvConfigAccessor, _ cloudevents.Client) adapter.Adapter {
-		return &controller{
-			logger:   logging.FromContext(ctx),
-			clients:  clients,
-			interval: 5 * time.Second,
+	return func(ctx context.Context, env adapter.EnvConfigAccessor, ceClient cloudevents.Client) adapter.Adapter {
+		c := &controller{
+			logger:          logging.FromContext(ctx),
+			clients:         clients,
+			interval:        defaultHeartbeatInterval,
+			tektonNamespace: defaultTektonNamespace,
+			maxPayloadSize:  defaultMaxPayloadSize,
+			ceClient:        ceClient,
+			sink:            env.GetSink(),
+			namespace:       env.GetNamespace(),
+			configName:      DefaultConfigName,
+		}
+		if e, ok := env.(*envConfig); ok {
+			c.signatureSecret = e.SignatureSecret
+			c.insecureUnsigned = e.InsecureUnsigned
+			c.encryptionSecret = e.EncryptionSecret
+			c.tlsSecret = e.TLSSecret
+			c.tlsClientCASecret = e.TLSClientCASecret
+			c.mode = v1alpha1.MinionMode(e.Mode)
+			c.minionName = e.MinionName
+			c.configName = e.ConfigName
+			c.tektonNamespace = e.TektonNamespace
+			if e.HeartbeatInterval > 0 {
+				c.interval = e.HeartbeatInterval
+			}
+			if q, err := resource.ParseQuantity(e.MaxPayloadSize); err == nil && q.Value() > 0 {
+				c.maxPayloadSize = q.Value()
+			} else {
+				c.logger.Warnf("invalid ARMADA_MAX_PAYLOAD_SIZE %q, using %d bytes", e.MaxPayloadSize, c.maxPayloadSize)
+			}
 		}
+		c.assembler = payload.NewAssembler(c.maxPayloadSize, chunkedEvents*c.maxPayloadSize, chunkTTL)
+		return c
 	}
 }
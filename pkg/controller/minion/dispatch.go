@@ -0,0 +1,53 @@
+package minion
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openshift-pipelines/tekton-armadas/pkg/apis/armada"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/kmeta"
+)
+
+// suffixLength is the number of characters of the idempotency key appended to
+// the name of a run clashing with a run of another dispatch.
+const suffixLength = 8
+
+// findByKey returns the name of the run created for the idempotency key
+// selector, empty if there is none.
+type findByKey func(ctx context.Context, selector string) (string, error)
+
+// createRun creates the run once per idempotency key and returns its name and
+// whether it has been created. When a run of another dispatch has the same
+// name, the run is created with the name suffixed with the key.
+func createRun[T metav1.Object](ctx context.Context, client resourceClient[T], find findByKey, run T, key string) (string, bool, error) {
+	if key != "" {
+		if len(key) < suffixLength {
+			return "", false, fmt.Errorf("%w: idempotency key %q is shorter than %d characters", errInvalidEvent, key, suffixLength)
+		}
+		name, err := find(ctx, armada.LabelIdempotencyKey+"="+key)
+		if err != nil {
+			return "", false, err
+		}
+		if name != "" {
+			return name, false, nil
+		}
+
+		if run.GetName() != "" {
+			_, err := client.Get(ctx, run.GetName(), metav1.GetOptions{})
+			switch {
+			case err == nil:
+				run.SetName(kmeta.ChildName(run.GetName(), "-"+key[:suffixLength]))
+			case !errors.IsNotFound(err):
+				return "", false, err
+			}
+		}
+	}
+
+	created, err := client.Create(ctx, run, metav1.CreateOptions{})
+	if err != nil {
+		return "", false, err
+	}
+	return created.GetName(), true, nil
+}
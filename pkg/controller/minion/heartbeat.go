@@ -0,0 +1,129 @@
+package minion
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/openshift-pipelines/tekton-armadas/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	// tektonInfoConfigName is the ConfigMap where Tekton Pipelines publishes
+	// its version.
+	tektonInfoConfigName = "pipelines-info"
+	tektonVersionKey     = "version"
+)
+
+// minionVersion returns the version the minion controller has been built at.
+func minionVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" {
+			return setting.Value
+		}
+	}
+	return info.Main.Version
+}
+
+// tektonVersion returns the version of Tekton Pipelines, empty when it cannot
+// be found.
+func (c *controller) tektonVersion(ctx context.Context) (string, error) {
+	cm, err := c.clients.Kube.CoreV1().ConfigMaps(c.tektonNamespace).Get(ctx, tektonInfoConfigName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("failed to get tekton version: %w", err)
+	}
+	return cm.Data[tektonVersionKey], nil
+}
+
+// allocatable returns the sum of the allocatable resources of the nodes.
+func (c *controller) allocatable(ctx context.Context) (corev1.ResourceList, error) {
+	nodes, err := c.clients.Kube.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	total := corev1.ResourceList{}
+	for _, node := range nodes.Items {
+		for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory, corev1.ResourcePods} {
+			quantity, ok := node.Status.Allocatable[name]
+			if !ok {
+				continue
+			}
+			sum := total[name]
+			sum.Add(quantity)
+			total[name] = sum
+		}
+	}
+	return total, nil
+}
+
+// heartbeatEvent returns the heartbeat with the current state of the minion.
+func (c *controller) heartbeatEvent(ctx context.Context, listers statusListers) (types.ArmadaHeartbeatEvent, error) {
+	hb := types.ArmadaHeartbeatEvent{
+		Minion:        c.minionName,
+		Version:       minionVersion(),
+		SchemaVersion: types.SchemaVersion,
+	}
+
+	prs, err := listers.pipelineRuns.List(labels.Everything())
+	if err != nil {
+		return hb, fmt.Errorf("failed to list pipelineruns: %w", err)
+	}
+	for _, pr := range prs {
+		switch {
+		case pr.IsDone():
+		case pr.HasStarted():
+			hb.RunningPipelineRuns++
+		default:
+			hb.PendingPipelineRuns++
+		}
+	}
+
+	if hb.TektonVersion, err = c.tektonVersion(ctx); err != nil {
+		return hb, err
+	}
+	if hb.Allocatable, err = c.allocatable(ctx); err != nil {
+		return hb, err
+	}
+	return hb, nil
+}
+
+// sendHeartbeat reports the health of the minion to the orchestrator.
+func (c *controller) sendHeartbeat(ctx context.Context, listers statusListers) error {
+	hb, err := c.heartbeatEvent(ctx, listers)
+	if err != nil {
+		return err
+	}
+	response, err := c.sendToOrchestrator(ctx, types.EventTypeHeartbeat, hb)
+	if err != nil {
+		return fmt.Errorf("failed to send heartbeat: %w", err)
+	}
+	return response.Body.Close()
+}
+
+// startHeartbeat sends a heartbeat to the orchestrator at every interval
+// until the context is done.
+func (c *controller) startHeartbeat(ctx context.Context, listers statusListers) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		if err := c.sendHeartbeat(ctx, listers); err != nil {
+			c.logger.Warnf("failed to report the health of the minion: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
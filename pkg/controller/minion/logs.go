@@ -0,0 +1,203 @@
+package minion
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/openshift-pipelines/tekton-armadas/pkg/types"
+	pipelineapi "github.com/tektoncd/pipeline/pkg/apis/pipeline"
+	tektonv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// logsPollInterval is how often the TaskRuns and their containers are
+	// checked again when following the logs.
+	logsPollInterval = 2 * time.Second
+	// maxLogLineSize is the longest log line streamed.
+	maxLogLineSize = 1024 * 1024
+)
+
+// flushWriter flushes the response after each write so the logs are
+// streamed as they come.
+type flushWriter struct {
+	w http.ResponseWriter
+}
+
+func (fw flushWriter) Write(p []byte) (int, error) {
+	n, err := fw.w.Write(p)
+	if err == nil {
+		err = http.NewResponseController(fw.w).Flush()
+	}
+	return n, err
+}
+
+// orderedTaskRuns returns the TaskRuns of the PipelineRun in the order of the
+// pipeline tasks, the finally tasks last.
+func (c *controller) orderedTaskRuns(ctx context.Context, pr *tektonv1.PipelineRun) ([]tektonv1.TaskRun, error) {
+	trs, err := c.clients.Tekton.TektonV1().TaskRuns(pr.GetNamespace()).List(ctx, metav1.ListOptions{
+		LabelSelector: pipelineapi.PipelineRunLabelKey + "=" + pr.GetName(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list taskruns of pipelinerun %s: %w", pr.GetName(), err)
+	}
+
+	order := map[string]int{}
+	if spec := pr.Status.PipelineSpec; spec != nil {
+		for i, pt := range append(append([]tektonv1.PipelineTask{}, spec.Tasks...), spec.Finally...) {
+			order[pt.Name] = i
+		}
+	}
+	items := trs.Items
+	sort.SliceStable(items, func(i, j int) bool {
+		oi, oj := order[items[i].GetLabels()[pipelineapi.PipelineTaskLabelKey]], order[items[j].GetLabels()[pipelineapi.PipelineTaskLabelKey]]
+		if oi != oj {
+			return oi < oj
+		}
+		return items[i].GetName() < items[j].GetName()
+	})
+	return items, nil
+}
+
+// streamStep writes the logs of the step container prefixed with the task and
+// step names. When following, it waits for the container to start until the
+// TaskRun is done.
+func (c *controller) streamStep(ctx context.Context, w io.Writer, tr *tektonv1.TaskRun, step tektonv1.StepState, follow bool) error {
+	prefix := fmt.Sprintf("[%s : %s] ", tr.GetLabels()[pipelineapi.PipelineTaskLabelKey], step.Name)
+	for {
+		stream, err := c.clients.Kube.CoreV1().Pods(tr.GetNamespace()).GetLogs(tr.Status.PodName, &corev1.PodLogOptions{
+			Container: step.Container,
+			Follow:    follow,
+		}).Stream(ctx)
+		if err == nil {
+			defer stream.Close()
+			scanner := bufio.NewScanner(stream)
+			scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxLogLineSize)
+			for scanner.Scan() {
+				if _, err := fmt.Fprintf(w, "%s%s\n", prefix, scanner.Text()); err != nil {
+					return err
+				}
+			}
+			return scanner.Err()
+		}
+		if !follow || tr.IsDone() {
+			_, err = fmt.Fprintf(w, "%scannot get logs: %v\n", prefix, err)
+			return err
+		}
+		// the container has not started yet, or its pod is gone and the
+		// TaskRun is about to be done
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(logsPollInterval):
+		}
+		latest, getErr := c.clients.Tekton.TektonV1().TaskRuns(tr.GetNamespace()).Get(ctx, tr.GetName(), metav1.GetOptions{})
+		if getErr != nil {
+			_, err = fmt.Fprintf(w, "%scannot get logs: %v\n", prefix, getErr)
+			return err
+		}
+		tr = latest
+	}
+}
+
+// streamLogs writes the logs of the steps of the TaskRuns of the PipelineRun
+// in pipeline order. When following, it waits for the TaskRuns to come until
+// the PipelineRun is done.
+func (c *controller) streamLogs(ctx context.Context, w io.Writer, pr *tektonv1.PipelineRun, follow bool) error {
+	streamed := map[string]bool{}
+	for {
+		latest, err := c.clients.Tekton.TektonV1().PipelineRuns(pr.GetNamespace()).Get(ctx, pr.GetName(), metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		trs, err := c.orderedTaskRuns(ctx, latest)
+		if err != nil {
+			return err
+		}
+
+		for i := range trs {
+			tr := &trs[i]
+			if streamed[tr.GetName()] {
+				continue
+			}
+			if tr.Status.PodName == "" || len(tr.Status.Steps) == 0 {
+				if follow && !tr.IsDone() {
+					// keep the order, wait for the pod of this TaskRun
+					break
+				}
+				streamed[tr.GetName()] = true
+				continue
+			}
+			for _, step := range tr.Status.Steps {
+				if err := c.streamStep(ctx, w, tr, step, follow); err != nil {
+					return err
+				}
+			}
+			streamed[tr.GetName()] = true
+		}
+
+		if !follow || (latest.IsDone() && len(streamed) == len(trs)) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(logsPollInterval):
+		}
+	}
+}
+
+// handleLogs streams the logs of the PipelineRun created for a source
+// PipelineRun to the orchestrator.
+func (c *controller) handleLogs() http.HandlerFunc {
+	return func(response http.ResponseWriter, request *http.Request) {
+		if c.verifiesSignatures() {
+			if err := c.verifySignature(request); err != nil {
+				c.logger.Errorf("rejecting logs request: %v", err)
+				c.writeResponse(response, http.StatusUnauthorized, err.Error())
+				return
+			}
+		}
+
+		// the type is signed, a dispatch or a control event cannot be
+		// replayed as a logs request
+		if eventType := request.Header.Get("Ce-Type"); eventType != types.EventTypeLogs {
+			c.writeResponse(response, http.StatusBadRequest, fmt.Sprintf("unexpected type %q for a logs request", eventType))
+			return
+		}
+
+		lr := types.ArmadaLogsRequest{}
+		if err := json.NewDecoder(request.Body).Decode(&lr); err != nil {
+			c.writeResponse(response, http.StatusBadRequest, "invalid logs request")
+			return
+		}
+		if errs := validation.IsValidLabelValue(lr.IdempotencyKey); lr.IdempotencyKey == "" || len(errs) > 0 {
+			c.writeResponse(response, http.StatusBadRequest, "invalid idempotency key in logs request")
+			return
+		}
+		ctx := request.Context()
+		pr, err := c.findDispatched(ctx, lr.IdempotencyKey)
+		if err != nil {
+			c.writeResponse(response, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if pr == nil {
+			c.writeResponse(response, http.StatusNotFound, fmt.Sprintf("no pipelinerun found for %s/%s", lr.Namespace, lr.Name))
+			return
+		}
+
+		response.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		response.WriteHeader(http.StatusOK)
+		if err := c.streamLogs(ctx, flushWriter{w: response}, pr, lr.Follow); err != nil {
+			c.logger.Errorf("failed to stream logs of pipelinerun %s: %v", pr.GetName(), err)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package minion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/openshift-pipelines/tekton-armadas/pkg/apis/armada"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// errForbiddenNamespace is returned when a run targets a namespace of the
+// deny list.
+var errForbiddenNamespace = errors.New("forbidden namespace")
+
+// targetNamespace returns the namespace the run of the source namespace is
+// created in according to the mapping of the configuration.
+func (cfg *minionConfig) targetNamespace(source string, runLabels map[string]string) (string, error) {
+	var target string
+	switch cfg.NamespaceMapping {
+	case namespaceMappingPrefix:
+		target = cfg.NamespacePrefix + source + cfg.NamespaceSuffix
+	case namespaceMappingStatic:
+		mapped, ok := cfg.NamespaceMap[source]
+		if !ok {
+			return "", fmt.Errorf("%w: namespace %s is not mapped", errInvalidEvent, source)
+		}
+		target = mapped
+	case namespaceMappingTenant:
+		tenant := runLabels[armada.LabelTenant]
+		if tenant == "" {
+			return "", fmt.Errorf("%w: run has no %s label", errInvalidEvent, armada.LabelTenant)
+		}
+		target = cfg.NamespacePrefix + tenant + cfg.NamespaceSuffix
+	default:
+		target = source
+	}
+
+	if errs := validation.IsDNS1123Label(target); len(errs) > 0 {
+		return "", fmt.Errorf("%w: invalid target namespace %q: %s", errInvalidEvent, target, strings.Join(errs, ", "))
+	}
+	if cfg.DenyNamespaces[target] {
+		return "", fmt.Errorf("%w: %s", errForbiddenNamespace, target)
+	}
+	return target, nil
+}
+
+// ensureNamespace creates the namespace with the labels and the
+// ServiceAccount of the configuration when it does not exist and the
+// configuration allows it.
+func (c *controller) ensureNamespace(ctx context.Context, cfg *minionConfig, namespace string) error {
+	_, err := c.clients.Kube.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		return nil
+	case !apierrors.IsNotFound(err):
+		return fmt.Errorf("failed to get namespace %s: %w", namespace, err)
+	case !cfg.AutoCreateNamespace:
+		return fmt.Errorf("namespace %s does not exist and %s is not enabled", namespace, namespaceAutoCreateKey)
+	}
+
+	labels := map[string]string{armada.LabelDispatched: "true"}
+	for k, v := range cfg.NamespaceLabels {
+		labels[k] = v
+	}
+	_, err = c.clients.Kube.CoreV1().Namespaces().Create(ctx, &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: namespace, Labels: labels},
+	}, metav1.CreateOptions{})
+	switch {
+	case err == nil:
+		c.logger.Infof("namespace %s has been created", namespace)
+	case !apierrors.IsAlreadyExists(err):
+		return fmt.Errorf("failed to create namespace %s: %w", namespace, err)
+	}
+
+	if cfg.NamespaceServiceAccount == "" {
+		return nil
+	}
+	if _, err := c.clients.Kube.CoreV1().ServiceAccounts(namespace).Create(ctx, &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: cfg.NamespaceServiceAccount},
+	}, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create serviceaccount %s in namespace %s: %w", cfg.NamespaceServiceAccount, namespace, err)
+	}
+	return nil
+}
+
+// prepareNamespace returns the namespace the runs of the event are created
+// in, creating it if needed.
+func (c *controller) prepareNamespace(ctx context.Context, cfg *minionConfig, source string, runLabels map[string]string) (string, error) {
+	namespace, err := cfg.targetNamespace(source, runLabels)
+	if err != nil {
+		return "", err
+	}
+	if err := c.ensureNamespace(ctx, cfg, namespace); err != nil {
+		return "", err
+	}
+	return namespace, nil
+}
@@ -0,0 +1,194 @@
+package minion
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/openshift-pipelines/tekton-armadas/pkg/apis/armada"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestTargetNamespace(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    map[string]string
+		source  string
+		labels  map[string]string
+		want    string
+		wantErr error
+	}{
+		{
+			name:   "identity",
+			source: "team-a",
+			want:   "team-a",
+		},
+		{
+			name: "prefix and suffix",
+			data: map[string]string{
+				namespaceMappingKey: namespaceMappingPrefix,
+				namespacePrefixKey:  "ci-",
+				namespaceSuffixKey:  "-runs",
+			},
+			source: "team-a",
+			want:   "ci-team-a-runs",
+		},
+		{
+			name: "static",
+			data: map[string]string{
+				namespaceMappingKey: namespaceMappingStatic,
+				namespaceMapKey:     "team-a: ci-team-a",
+			},
+			source: "team-a",
+			want:   "ci-team-a",
+		},
+		{
+			name: "static not mapped",
+			data: map[string]string{
+				namespaceMappingKey: namespaceMappingStatic,
+				namespaceMapKey:     "team-a: ci-team-a",
+			},
+			source:  "team-b",
+			wantErr: errInvalidEvent,
+		},
+		{
+			name: "tenant",
+			data: map[string]string{
+				namespaceMappingKey: namespaceMappingTenant,
+				namespacePrefixKey:  "tenant-",
+			},
+			source: "team-a",
+			labels: map[string]string{armada.LabelTenant: "acme"},
+			want:   "tenant-acme",
+		},
+		{
+			name:    "tenant without label",
+			data:    map[string]string{namespaceMappingKey: namespaceMappingTenant},
+			source:  "team-a",
+			wantErr: errInvalidEvent,
+		},
+		{
+			name: "invalid target",
+			data: map[string]string{
+				namespaceMappingKey: namespaceMappingPrefix,
+				namespacePrefixKey:  "CI_",
+			},
+			source:  "team-a",
+			wantErr: errInvalidEvent,
+		},
+		{
+			name:    "default denied namespace",
+			source:  "kube-system",
+			wantErr: errForbiddenNamespace,
+		},
+		{
+			name:    "default denied namespace with an empty deny list",
+			data:    map[string]string{namespaceDenyListKey: ""},
+			source:  "kube-public",
+			wantErr: errForbiddenNamespace,
+		},
+		{
+			name:    "default denied namespace with a deny list",
+			data:    map[string]string{namespaceDenyListKey: "production"},
+			source:  "kube-node-lease",
+			wantErr: errForbiddenNamespace,
+		},
+		{
+			name:    "denied namespace of the deny list",
+			data:    map[string]string{namespaceDenyListKey: "production, staging"},
+			source:  "staging",
+			wantErr: errForbiddenNamespace,
+		},
+		{
+			name: "mapped to a denied namespace",
+			data: map[string]string{
+				namespaceMappingKey: namespaceMappingStatic,
+				namespaceMapKey:     "team-a: kube-system",
+			},
+			source:  "team-a",
+			wantErr: errForbiddenNamespace,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := newMinionConfigFromConfigMap(&corev1.ConfigMap{Data: tt.data})
+			if err != nil {
+				t.Fatalf("newMinionConfigFromConfigMap() = %v", err)
+			}
+			got, err := cfg.targetNamespace(tt.source, tt.labels)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("targetNamespace() = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("targetNamespace() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInvalidMinionConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		data map[string]string
+	}{
+		{name: "unknown mapping", data: map[string]string{namespaceMappingKey: "random"}},
+		{name: "invalid map", data: map[string]string{namespaceMapKey: "- team-a"}},
+		{name: "invalid boolean", data: map[string]string{namespaceAutoCreateKey: "maybe"}},
+		{name: "invalid timeout", data: map[string]string{policyMaxTimeoutKey: "forever"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newMinionConfigFromConfigMap(&corev1.ConfigMap{Data: tt.data}); err == nil {
+				t.Error("newMinionConfigFromConfigMap() = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestGetConfigDeniesOwnNamespaces(t *testing.T) {
+	tests := []struct {
+		name      string
+		configMap *corev1.ConfigMap
+	}{
+		{
+			name: "without configmap",
+		},
+		{
+			name: "with an empty deny list",
+			configMap: &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: DefaultConfigName, Namespace: "armadas"},
+				Data:       map[string]string{namespaceDenyListKey: ""},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+			if tt.configMap != nil {
+				if err := indexer.Add(tt.configMap); err != nil {
+					t.Fatal(err)
+				}
+			}
+			c := &controller{
+				namespace:       "armadas",
+				configName:      DefaultConfigName,
+				tektonNamespace: "tekton-pipelines",
+				configMapLister: corev1listers.NewConfigMapLister(indexer),
+			}
+			cfg, err := c.getConfig()
+			if err != nil {
+				t.Fatalf("getConfig() = %v", err)
+			}
+			for _, ns := range []string{"armadas", "tekton-pipelines", "kube-system", "kube-public", "kube-node-lease"} {
+				if _, err := cfg.targetNamespace(ns, nil); !errors.Is(err, errForbiddenNamespace) {
+					t.Errorf("targetNamespace(%s) = %v, want %v", ns, err, errForbiddenNamespace)
+				}
+			}
+			if _, err := cfg.targetNamespace("team-a", nil); err != nil {
+				t.Errorf("targetNamespace(team-a) = %v", err)
+			}
+		})
+	}
+}
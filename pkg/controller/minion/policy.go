@@ -0,0 +1,322 @@
+package minion
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/openshift-pipelines/tekton-armadas/pkg/types"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/pod"
+	tektonv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The reasons the policy of the minion rejects a run for, returned in the
+// Response to the orchestrator.
+const (
+	PolicyReasonSourceNamespaceNotAllowed = "SourceNamespaceNotAllowed"
+	PolicyReasonRegistryNotAllowed        = "RegistryNotAllowed"
+	PolicyReasonHostNetworkForbidden      = "HostNetworkForbidden"
+	PolicyReasonPrivilegedForbidden       = "PrivilegedForbidden"
+	PolicyReasonTimeoutExceeded           = "TimeoutExceeded"
+	PolicyReasonServiceAccountNotAllowed  = "ServiceAccountNotAllowed"
+	PolicyReasonRemoteReferenceForbidden  = "RemoteReferenceForbidden"
+	PolicyReasonUnbundledReference        = "UnbundledReference"
+
+	// defaultServiceAccount is the ServiceAccount of the runs without one.
+	defaultServiceAccount = "default"
+)
+
+// policy is what the runs received from the orchestrator are allowed to do,
+// the empty lists allow everything.
+type policy struct {
+	// AllowedRegistries are the registries, optionally followed by a path,
+	// the images of the steps and sidecars must come from.
+	AllowedRegistries []string
+	AllowHostNetwork  bool
+	AllowPrivileged   bool
+	// MaxTimeout is the longest timeout of a run, the runs without timeout
+	// are rejected when set.
+	MaxTimeout              time.Duration
+	AllowedServiceAccounts  []string
+	AllowedSourceNamespaces []string
+}
+
+// policyViolation is the error of a run rejected by the policy.
+type policyViolation struct {
+	Reason  string
+	Message string
+}
+
+func (v *policyViolation) Error() string {
+	return fmt.Sprintf("rejected by policy: %s: %s", v.Reason, v.Message)
+}
+
+func violation(reason, format string, args ...any) error {
+	return &policyViolation{Reason: reason, Message: fmt.Sprintf(format, args...)}
+}
+
+// bundled are the names of the Tasks and the Pipelines bundled with a run.
+type bundled struct {
+	tasks     map[string]bool
+	pipelines map[string]bool
+}
+
+func bundledRefs(tt types.Types) bundled {
+	b := bundled{tasks: map[string]bool{}, pipelines: map[string]bool{}}
+	for _, task := range tt.Tekton.Tasks {
+		b.tasks[task.GetName()] = true
+	}
+	for _, pipeline := range tt.Tekton.Pipelines {
+		b.pipelines[pipeline.GetName()] = true
+	}
+	return b
+}
+
+// checksReferences checks if the steps of the runs are restricted, the tasks
+// and the pipelines they refer to must then be bundled to be checked too.
+func (p *policy) checksReferences() bool {
+	return len(p.AllowedRegistries) > 0 || !p.AllowPrivileged
+}
+
+// checkTaskRef checks the task is bundled with the run when the steps are
+// restricted, the tasks resolved on the minion or not bundled are not known
+// when the run is admitted.
+func (p *policy) checkTaskRef(ref *tektonv1.TaskRef, b bundled) error {
+	if ref == nil || !p.checksReferences() {
+		return nil
+	}
+	if ref.Resolver != "" {
+		return violation(PolicyReasonRemoteReferenceForbidden, "task resolved with the %s resolver cannot be checked", ref.Resolver)
+	}
+	if (ref.Kind != "" && ref.Kind != tektonv1.NamespacedTaskKind) || !b.tasks[ref.Name] {
+		return violation(PolicyReasonUnbundledReference, "task %s has not been bundled with the run and cannot be checked", ref.Name)
+	}
+	return nil
+}
+
+// checkPipelineRef checks the pipeline is bundled with the run when the steps
+// are restricted, the same way as checkTaskRef.
+func (p *policy) checkPipelineRef(ref *tektonv1.PipelineRef, b bundled) error {
+	if ref == nil || !p.checksReferences() {
+		return nil
+	}
+	if ref.Resolver != "" {
+		return violation(PolicyReasonRemoteReferenceForbidden, "pipeline resolved with the %s resolver cannot be checked", ref.Resolver)
+	}
+	if !b.pipelines[ref.Name] {
+		return violation(PolicyReasonUnbundledReference, "pipeline %s has not been bundled with the run and cannot be checked", ref.Name)
+	}
+	return nil
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// imageRepository returns the registry and the repository of the image the
+// way the container runtimes resolve them, the images without registry come
+// from index.docker.io and the official ones from its library repository.
+func imageRepository(image string) (string, string, error) {
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return "", "", err
+	}
+	return ref.Context().RegistryStr(), ref.Context().RepositoryStr(), nil
+}
+
+// allowedRepository checks the repository of the registry is under the
+// allowed registry, optionally followed by a path. docker.io and
+// index.docker.io are the same registry.
+func allowedRepository(allowed, registry, repository string) bool {
+	host, path, _ := strings.Cut(strings.TrimSuffix(allowed, "/"), "/")
+	allowedRegistry, err := name.NewRegistry(host)
+	if err != nil || allowedRegistry.RegistryStr() != registry {
+		return false
+	}
+	return path == "" || repository == path || strings.HasPrefix(repository, path+"/")
+}
+
+// checkImage checks the image comes from an allowed registry.
+func (p *policy) checkImage(image string) error {
+	if image == "" || len(p.AllowedRegistries) == 0 {
+		return nil
+	}
+	registry, repository, err := imageRepository(image)
+	if err != nil {
+		return violation(PolicyReasonRegistryNotAllowed, "image %s cannot be checked: %v", image, err)
+	}
+	for _, allowed := range p.AllowedRegistries {
+		if allowedRepository(allowed, registry, repository) {
+			return nil
+		}
+	}
+	return violation(PolicyReasonRegistryNotAllowed, "image %s is not from an allowed registry", image)
+}
+
+// checkContainer checks the image and the security context of a step or a
+// sidecar.
+func (p *policy) checkContainer(kind, name, image string, sc *corev1.SecurityContext) error {
+	if err := p.checkImage(image); err != nil {
+		return err
+	}
+	if !p.AllowPrivileged && sc != nil && sc.Privileged != nil && *sc.Privileged {
+		return violation(PolicyReasonPrivilegedForbidden, "%s %s is privileged", kind, name)
+	}
+	return nil
+}
+
+// checkTaskSpec checks the steps and the sidecars of the task.
+func (p *policy) checkTaskSpec(spec *tektonv1.TaskSpec) error {
+	if spec == nil {
+		return nil
+	}
+	if spec.StepTemplate != nil {
+		if err := p.checkContainer("step template", "", spec.StepTemplate.Image, spec.StepTemplate.SecurityContext); err != nil {
+			return err
+		}
+	}
+	for _, step := range spec.Steps {
+		if err := p.checkContainer("step", step.Name, step.Image, step.SecurityContext); err != nil {
+			return err
+		}
+	}
+	for _, sidecar := range spec.Sidecars {
+		if err := p.checkContainer("sidecar", sidecar.Name, sidecar.Image, sidecar.SecurityContext); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkPipelineSpec checks the embedded tasks of the pipeline and the tasks
+// it refers to.
+func (p *policy) checkPipelineSpec(spec *tektonv1.PipelineSpec, b bundled) error {
+	if spec == nil {
+		return nil
+	}
+	for _, pt := range append(append([]tektonv1.PipelineTask{}, spec.Tasks...), spec.Finally...) {
+		if err := p.checkTaskRef(pt.TaskRef, b); err != nil {
+			return err
+		}
+		if pt.TaskSpec == nil {
+			continue
+		}
+		if err := p.checkTaskSpec(&pt.TaskSpec.TaskSpec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *policy) checkPodTemplate(tpl *pod.PodTemplate) error {
+	if !p.AllowHostNetwork && tpl != nil && tpl.HostNetwork {
+		return violation(PolicyReasonHostNetworkForbidden, "pod template uses the host network")
+	}
+	return nil
+}
+
+func (p *policy) checkServiceAccount(name string) error {
+	if name == "" {
+		name = defaultServiceAccount
+	}
+	if len(p.AllowedServiceAccounts) > 0 && !contains(p.AllowedServiceAccounts, name) {
+		return violation(PolicyReasonServiceAccountNotAllowed, "serviceaccount %s is not allowed", name)
+	}
+	return nil
+}
+
+// checkTimeout checks the timeout of a run, a nil timeout is the default
+// of the cluster and a zero timeout means no timeout.
+func (p *policy) checkTimeout(timeout *metav1.Duration) error {
+	if p.MaxTimeout == 0 || timeout == nil {
+		return nil
+	}
+	if timeout.Duration == 0 || timeout.Duration > p.MaxTimeout {
+		return violation(PolicyReasonTimeoutExceeded, "timeout %s is over the maximum of %s", timeout.Duration, p.MaxTimeout)
+	}
+	return nil
+}
+
+func (p *policy) checkPipelineRun(pr *tektonv1.PipelineRun, b bundled) error {
+	if pr.Spec.Timeouts != nil {
+		if err := p.checkTimeout(pr.Spec.Timeouts.Pipeline); err != nil {
+			return err
+		}
+	}
+	if err := p.checkServiceAccount(pr.Spec.TaskRunTemplate.ServiceAccountName); err != nil {
+		return err
+	}
+	if err := p.checkPodTemplate(pr.Spec.TaskRunTemplate.PodTemplate); err != nil {
+		return err
+	}
+	for _, spec := range pr.Spec.TaskRunSpecs {
+		if spec.ServiceAccountName != "" {
+			if err := p.checkServiceAccount(spec.ServiceAccountName); err != nil {
+				return err
+			}
+		}
+		if err := p.checkPodTemplate(spec.PodTemplate); err != nil {
+			return err
+		}
+	}
+	if err := p.checkPipelineRef(pr.Spec.PipelineRef, b); err != nil {
+		return err
+	}
+	return p.checkPipelineSpec(pr.Spec.PipelineSpec, b)
+}
+
+func (p *policy) checkTaskRun(tr *tektonv1.TaskRun, b bundled) error {
+	if err := p.checkTimeout(tr.Spec.Timeout); err != nil {
+		return err
+	}
+	if err := p.checkServiceAccount(tr.Spec.ServiceAccountName); err != nil {
+		return err
+	}
+	if err := p.checkPodTemplate(tr.Spec.PodTemplate); err != nil {
+		return err
+	}
+	if err := p.checkTaskRef(tr.Spec.TaskRef, b); err != nil {
+		return err
+	}
+	return p.checkTaskSpec(tr.Spec.TaskSpec)
+}
+
+// admit checks the runs of the event and the resources bundled with them
+// against the policy. When the steps are restricted, the runs referring to
+// tasks or pipelines resolved remotely or not bundled are rejected as they
+// are not known at this point and could not be checked.
+func (p *policy) admit(aEvent types.ArmadaEvent, tt types.Types) error {
+	if len(p.AllowedSourceNamespaces) > 0 && !contains(p.AllowedSourceNamespaces, aEvent.Namespace) {
+		return violation(PolicyReasonSourceNamespaceNotAllowed, "source namespace %s is not allowed", aEvent.Namespace)
+	}
+	b := bundledRefs(tt)
+	for _, pr := range tt.Tekton.PipelineRuns {
+		if err := p.checkPipelineRun(pr, b); err != nil {
+			return err
+		}
+	}
+	for _, tr := range tt.Tekton.TaskRuns {
+		if err := p.checkTaskRun(tr, b); err != nil {
+			return err
+		}
+	}
+	for _, pipeline := range tt.Tekton.Pipelines {
+		if err := p.checkPipelineSpec(&pipeline.Spec, b); err != nil {
+			return err
+		}
+	}
+	for _, task := range tt.Tekton.Tasks {
+		if err := p.checkTaskSpec(&task.Spec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
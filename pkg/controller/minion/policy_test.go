@@ -0,0 +1,289 @@
+package minion
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/openshift-pipelines/tekton-armadas/pkg/types"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/pod"
+	tektonv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+// wantViolation checks the error is a policy violation with the reason, or
+// nil when the reason is empty.
+func wantViolation(t *testing.T, err error, reason string) {
+	t.Helper()
+	if reason == "" {
+		if err != nil {
+			t.Errorf("got %v, want no violation", err)
+		}
+		return
+	}
+	var v *policyViolation
+	if !errors.As(err, &v) {
+		t.Fatalf("got %v, want a %s violation", err, reason)
+	}
+	if v.Reason != reason {
+		t.Errorf("got a %s violation, want %s: %s", v.Reason, reason, v.Message)
+	}
+}
+
+func TestImageRepository(t *testing.T) {
+	tests := []struct {
+		image          string
+		wantRegistry   string
+		wantRepository string
+		wantErr        bool
+	}{
+		{image: "ubuntu", wantRegistry: "index.docker.io", wantRepository: "library/ubuntu"},
+		{image: "ubuntu:22.04", wantRegistry: "index.docker.io", wantRepository: "library/ubuntu"},
+		{image: "docker.io/ubuntu", wantRegistry: "index.docker.io", wantRepository: "library/ubuntu"},
+		{image: "docker.io/library/ubuntu", wantRegistry: "index.docker.io", wantRepository: "library/ubuntu"},
+		{image: "bitnami/kubectl", wantRegistry: "index.docker.io", wantRepository: "bitnami/kubectl"},
+		{image: "quay.io/org/image:v1", wantRegistry: "quay.io", wantRepository: "org/image"},
+		{image: "localhost:5000/image", wantRegistry: "localhost:5000", wantRepository: "image"},
+		{image: "registry.local/team/image@sha256:" + "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", wantRegistry: "registry.local", wantRepository: "team/image"},
+		{image: "$(params.image)", wantErr: true},
+		{image: "Invalid Image", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			registry, repository, err := imageRepository(tt.image)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("imageRepository() = %v, wantErr %t", err, tt.wantErr)
+			}
+			if registry != tt.wantRegistry || repository != tt.wantRepository {
+				t.Errorf("imageRepository() = %s %s, want %s %s", registry, repository, tt.wantRegistry, tt.wantRepository)
+			}
+		})
+	}
+}
+
+func TestCheckImage(t *testing.T) {
+	tests := []struct {
+		name       string
+		allowed    []string
+		image      string
+		wantReason string
+	}{
+		{name: "no restriction", image: "ubuntu"},
+		{name: "no image", allowed: []string{"quay.io"}},
+		{name: "registry", allowed: []string{"quay.io"}, image: "quay.io/org/image"},
+		{name: "registry with a trailing slash", allowed: []string{"quay.io/"}, image: "quay.io/org/image"},
+		{name: "path", allowed: []string{"quay.io/org"}, image: "quay.io/org/image"},
+		{name: "another path", allowed: []string{"quay.io/org"}, image: "quay.io/other/image", wantReason: PolicyReasonRegistryNotAllowed},
+		{name: "path prefix of another path", allowed: []string{"quay.io/org"}, image: "quay.io/organization/image", wantReason: PolicyReasonRegistryNotAllowed},
+		{name: "another registry", allowed: []string{"quay.io"}, image: "ghcr.io/org/image", wantReason: PolicyReasonRegistryNotAllowed},
+		{name: "registry as a path", allowed: []string{"quay.io"}, image: "evil.io/quay.io/image", wantReason: PolicyReasonRegistryNotAllowed},
+		{name: "official image with the canonical name", allowed: []string{"docker.io/library"}, image: "ubuntu"},
+		{name: "official image with the registry", allowed: []string{"docker.io/library"}, image: "docker.io/ubuntu"},
+		{name: "official image with the index name", allowed: []string{"index.docker.io/library"}, image: "ubuntu"},
+		{name: "user image not official", allowed: []string{"docker.io/library"}, image: "bitnami/kubectl", wantReason: PolicyReasonRegistryNotAllowed},
+		{name: "docker hub", allowed: []string{"docker.io"}, image: "bitnami/kubectl"},
+		{name: "docker hub not allowed", allowed: []string{"quay.io"}, image: "ubuntu", wantReason: PolicyReasonRegistryNotAllowed},
+		{name: "registry with a port", allowed: []string{"localhost:5000"}, image: "localhost:5000/image"},
+		{name: "unresolved parameter", allowed: []string{"docker.io"}, image: "$(params.image)", wantReason: PolicyReasonRegistryNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &policy{AllowedRegistries: tt.allowed, AllowPrivileged: true}
+			wantViolation(t, p.checkImage(tt.image), tt.wantReason)
+		})
+	}
+}
+
+func TestAdmit(t *testing.T) {
+	taskSpec := func(image string, privileged bool) *tektonv1.TaskSpec {
+		return &tektonv1.TaskSpec{Steps: []tektonv1.Step{{
+			Name:            "step",
+			Image:           image,
+			SecurityContext: &corev1.SecurityContext{Privileged: ptr.To(privileged)},
+		}}}
+	}
+	pipelineRun := func(spec tektonv1.PipelineRunSpec) types.Types {
+		return types.Types{Tekton: types.TektonTypes{PipelineRuns: []*tektonv1.PipelineRun{{
+			ObjectMeta: metav1.ObjectMeta{Name: "run"},
+			Spec:       spec,
+		}}}}
+	}
+	taskRun := func(spec tektonv1.TaskRunSpec) types.Types {
+		return types.Types{Tekton: types.TektonTypes{TaskRuns: []*tektonv1.TaskRun{{
+			ObjectMeta: metav1.ObjectMeta{Name: "run"},
+			Spec:       spec,
+		}}}}
+	}
+	embedded := func(spec *tektonv1.TaskSpec) *tektonv1.PipelineSpec {
+		return &tektonv1.PipelineSpec{Tasks: []tektonv1.PipelineTask{{
+			Name:     "task",
+			TaskSpec: &tektonv1.EmbeddedTask{TaskSpec: *spec},
+		}}}
+	}
+	restricted := policy{AllowedRegistries: []string{"quay.io"}}
+
+	tests := []struct {
+		name       string
+		policy     policy
+		namespace  string
+		types      types.Types
+		wantReason string
+	}{
+		{
+			name:      "allowed pipelinerun",
+			policy:    restricted,
+			namespace: "team-a",
+			types:     pipelineRun(tektonv1.PipelineRunSpec{PipelineSpec: embedded(taskSpec("quay.io/org/image", false))}),
+		},
+		{
+			name:       "source namespace not allowed",
+			policy:     policy{AllowedSourceNamespaces: []string{"team-a"}},
+			namespace:  "team-b",
+			types:      pipelineRun(tektonv1.PipelineRunSpec{}),
+			wantReason: PolicyReasonSourceNamespaceNotAllowed,
+		},
+		{
+			name:       "step image not allowed",
+			policy:     restricted,
+			types:      pipelineRun(tektonv1.PipelineRunSpec{PipelineSpec: embedded(taskSpec("ubuntu", false))}),
+			wantReason: PolicyReasonRegistryNotAllowed,
+		},
+		{
+			name:   "sidecar image not allowed",
+			policy: restricted,
+			types: taskRun(tektonv1.TaskRunSpec{TaskSpec: &tektonv1.TaskSpec{
+				Steps:    []tektonv1.Step{{Name: "step", Image: "quay.io/org/image"}},
+				Sidecars: []tektonv1.Sidecar{{Name: "sidecar", Image: "ghcr.io/org/image"}},
+			}}),
+			wantReason: PolicyReasonRegistryNotAllowed,
+		},
+		{
+			name:       "privileged step",
+			policy:     policy{},
+			types:      taskRun(tektonv1.TaskRunSpec{TaskSpec: taskSpec("ubuntu", true)}),
+			wantReason: PolicyReasonPrivilegedForbidden,
+		},
+		{
+			name:   "privileged step allowed",
+			policy: policy{AllowPrivileged: true},
+			types:  taskRun(tektonv1.TaskRunSpec{TaskSpec: taskSpec("ubuntu", true)}),
+		},
+		{
+			name:   "host network",
+			policy: policy{AllowPrivileged: true},
+			types: pipelineRun(tektonv1.PipelineRunSpec{TaskRunTemplate: tektonv1.PipelineTaskRunTemplate{
+				PodTemplate: &pod.PodTemplate{HostNetwork: true},
+			}}),
+			wantReason: PolicyReasonHostNetworkForbidden,
+		},
+		{
+			name:   "host network in a task run spec",
+			policy: policy{AllowPrivileged: true},
+			types: pipelineRun(tektonv1.PipelineRunSpec{TaskRunSpecs: []tektonv1.PipelineTaskRunSpec{{
+				PipelineTaskName: "task",
+				PodTemplate:      &pod.PodTemplate{HostNetwork: true},
+			}}}),
+			wantReason: PolicyReasonHostNetworkForbidden,
+		},
+		{
+			name:   "timeout over the maximum",
+			policy: policy{AllowPrivileged: true, MaxTimeout: time.Hour},
+			types: taskRun(tektonv1.TaskRunSpec{
+				Timeout: &metav1.Duration{Duration: 2 * time.Hour},
+			}),
+			wantReason: PolicyReasonTimeoutExceeded,
+		},
+		{
+			name:   "no timeout",
+			policy: policy{AllowPrivileged: true, MaxTimeout: time.Hour},
+			types: pipelineRun(tektonv1.PipelineRunSpec{Timeouts: &tektonv1.TimeoutFields{
+				Pipeline: &metav1.Duration{},
+			}}),
+			wantReason: PolicyReasonTimeoutExceeded,
+		},
+		{
+			name:   "default timeout",
+			policy: policy{AllowPrivileged: true, MaxTimeout: time.Hour},
+			types:  taskRun(tektonv1.TaskRunSpec{}),
+		},
+		{
+			name:       "default serviceaccount not allowed",
+			policy:     policy{AllowPrivileged: true, AllowedServiceAccounts: []string{"builder"}},
+			types:      taskRun(tektonv1.TaskRunSpec{}),
+			wantReason: PolicyReasonServiceAccountNotAllowed,
+		},
+		{
+			name:   "serviceaccount allowed",
+			policy: policy{AllowPrivileged: true, AllowedServiceAccounts: []string{"builder"}},
+			types:  taskRun(tektonv1.TaskRunSpec{ServiceAccountName: "builder"}),
+		},
+		{
+			name:   "serviceaccount of a task run spec not allowed",
+			policy: policy{AllowPrivileged: true, AllowedServiceAccounts: []string{"builder"}},
+			types: pipelineRun(tektonv1.PipelineRunSpec{
+				TaskRunTemplate: tektonv1.PipelineTaskRunTemplate{ServiceAccountName: "builder"},
+				TaskRunSpecs:    []tektonv1.PipelineTaskRunSpec{{PipelineTaskName: "task", ServiceAccountName: "admin"}},
+			}),
+			wantReason: PolicyReasonServiceAccountNotAllowed,
+		},
+		{
+			name:       "task resolved remotely",
+			policy:     restricted,
+			types:      taskRun(tektonv1.TaskRunSpec{TaskRef: &tektonv1.TaskRef{ResolverRef: tektonv1.ResolverRef{Resolver: "git"}}}),
+			wantReason: PolicyReasonRemoteReferenceForbidden,
+		},
+		{
+			name:       "pipeline resolved remotely",
+			policy:     restricted,
+			types:      pipelineRun(tektonv1.PipelineRunSpec{PipelineRef: &tektonv1.PipelineRef{ResolverRef: tektonv1.ResolverRef{Resolver: "bundles"}}}),
+			wantReason: PolicyReasonRemoteReferenceForbidden,
+		},
+		{
+			name:       "task not bundled",
+			policy:     restricted,
+			types:      taskRun(tektonv1.TaskRunSpec{TaskRef: &tektonv1.TaskRef{Name: "build"}}),
+			wantReason: PolicyReasonUnbundledReference,
+		},
+		{
+			name:   "bundled task checked",
+			policy: restricted,
+			types: types.Types{Tekton: types.TektonTypes{
+				TaskRuns: []*tektonv1.TaskRun{{Spec: tektonv1.TaskRunSpec{TaskRef: &tektonv1.TaskRef{Name: "build"}}}},
+				Tasks:    []*tektonv1.Task{{ObjectMeta: metav1.ObjectMeta{Name: "build"}, Spec: *taskSpec("ubuntu", false)}},
+			}},
+			wantReason: PolicyReasonRegistryNotAllowed,
+		},
+		{
+			name:   "bundled pipeline and task",
+			policy: restricted,
+			types: types.Types{Tekton: types.TektonTypes{
+				PipelineRuns: []*tektonv1.PipelineRun{{Spec: tektonv1.PipelineRunSpec{PipelineRef: &tektonv1.PipelineRef{Name: "ci"}}}},
+				Pipelines: []*tektonv1.Pipeline{{ObjectMeta: metav1.ObjectMeta{Name: "ci"}, Spec: tektonv1.PipelineSpec{
+					Tasks: []tektonv1.PipelineTask{{Name: "build", TaskRef: &tektonv1.TaskRef{Name: "build"}}},
+				}}},
+				Tasks: []*tektonv1.Task{{ObjectMeta: metav1.ObjectMeta{Name: "build"}, Spec: *taskSpec("quay.io/org/image", false)}},
+			}},
+		},
+		{
+			name:   "cluster task not bundled",
+			policy: restricted,
+			types: types.Types{Tekton: types.TektonTypes{
+				TaskRuns: []*tektonv1.TaskRun{{Spec: tektonv1.TaskRunSpec{TaskRef: &tektonv1.TaskRef{Name: "build", Kind: "ClusterTask"}}}},
+				Tasks:    []*tektonv1.Task{{ObjectMeta: metav1.ObjectMeta{Name: "build"}, Spec: *taskSpec("quay.io/org/image", false)}},
+			}},
+			wantReason: PolicyReasonUnbundledReference,
+		},
+		{
+			name:   "references not checked without restriction",
+			policy: policy{AllowPrivileged: true},
+			types:  taskRun(tektonv1.TaskRunSpec{TaskRef: &tektonv1.TaskRef{ResolverRef: tektonv1.ResolverRef{Resolver: "git"}}}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wantViolation(t, tt.policy.admit(types.ArmadaEvent{Namespace: tt.namespace}, tt.types), tt.wantReason)
+		})
+	}
+}
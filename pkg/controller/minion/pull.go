@@ -0,0 +1,132 @@
+package minion
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	cehttp "github.com/cloudevents/sdk-go/v2/protocol/http"
+	"github.com/openshift-pipelines/tekton-armadas/pkg/signature"
+	"github.com/openshift-pipelines/tekton-armadas/pkg/types"
+)
+
+const (
+	// pullTimeout is above the time the orchestrator holds a poll.
+	pullTimeout = 45 * time.Second
+	// pullRetryInterval is how long to wait before polling again after an error.
+	pullRetryInterval = 5 * time.Second
+)
+
+// sendToOrchestrator sends the event to the orchestrator, signed when the
+// minion has a signature secret, and returns the response.
+func (c *controller) sendToOrchestrator(ctx context.Context, eventType string, data any) (*http.Response, error) {
+	event := cloudevents.NewEvent()
+	event.SetSource(types.EventSource)
+	event.SetType(eventType)
+	event.SetID(types.UUID())
+	if err := event.SetData(cloudevents.ApplicationJSON, data); err != nil {
+		return nil, fmt.Errorf("failed to set data: %w", err)
+	}
+
+	request, err := cehttp.NewHTTPRequestFromEvent(ctx, c.sink, event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	client := &http.Client{Timeout: pullTimeout}
+	if c.signatureSecret != "" {
+		secret, err := c.getSecretKey(c.signatureSecret, signature.SecretKey)
+		if err != nil {
+			return nil, err
+		}
+		client.Transport = &signature.Transport{Secret: secret}
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode >= http.StatusBadRequest {
+		_ = response.Body.Close()
+		return nil, fmt.Errorf("orchestrator answered %s", response.Status)
+	}
+	return response, nil
+}
+
+// pull waits for the events the orchestrator has for the minion, each of
+// them signed with the secret of the minion.
+func (c *controller) pull(ctx context.Context) ([]cloudevents.Event, error) {
+	response, err := c.sendToOrchestrator(ctx, types.EventTypePull, types.ArmadaPullEvent{Minion: c.minionName})
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	events := []cloudevents.Event{}
+	if err := json.NewDecoder(response.Body).Decode(&events); err != nil {
+		return nil, fmt.Errorf("failed to decode pulled events: %w", err)
+	}
+	return events, nil
+}
+
+// acknowledge tells the orchestrator which events have been applied and which
+// ones have been rejected.
+func (c *controller) acknowledge(ctx context.Context, ack types.ArmadaAckEvent) error {
+	response, err := c.sendToOrchestrator(ctx, types.EventTypeAck, ack)
+	if err != nil {
+		return err
+	}
+	return response.Body.Close()
+}
+
+// startPuller polls the orchestrator for events until the context is done,
+// applying and acknowledging them as they come. The events failing for a
+// reason of their own are acknowledged as rejected, the others are delivered
+// again once the orchestrator stops waiting for their acknowledgement.
+func (c *controller) startPuller(ctx context.Context) error {
+	if c.minionName == "" {
+		return errors.New("ARMADA_MINION_NAME is required in pull mode")
+	}
+	if c.signatureSecret == "" {
+		return errors.New("ARMADA_SIGNATURE_SECRET is required in pull mode, the orchestrator rejects the unsigned polls")
+	}
+	c.logger.Infof("polling %s for the events of minion %s", c.sink, c.minionName)
+
+	for ctx.Err() == nil {
+		events, err := c.pull(ctx)
+		if err != nil {
+			c.logger.Errorf("failed to pull events: %v", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(pullRetryInterval):
+			}
+			continue
+		}
+
+		ack := types.ArmadaAckEvent{Minion: c.minionName, IDs: []string{}}
+		for _, event := range events {
+			if err := c.verifyEventSignature(event); err != nil {
+				c.logger.Errorf("rejecting pulled event %s: %v", event.ID(), err)
+				continue
+			}
+			if err := c.processEvent(ctx, event); err != nil {
+				c.logger.Errorf("failed to process pulled event %s: %+v", event.ID(), err)
+				if body, ok := rejection(err); ok {
+					ack.Failures = append(ack.Failures, types.ArmadaAckFailure{ID: event.ID(), Reason: body.Reason, Message: body.Message})
+				}
+				continue
+			}
+			ack.IDs = append(ack.IDs, event.ID())
+		}
+		if len(ack.IDs) == 0 && len(ack.Failures) == 0 {
+			continue
+		}
+		if err := c.acknowledge(ctx, ack); err != nil {
+			c.logger.Errorf("failed to acknowledge events: %v", err)
+		}
+	}
+	return nil
+}
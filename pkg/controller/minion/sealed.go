@@ -0,0 +1,115 @@
+package minion
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/openshift-pipelines/tekton-armadas/pkg/apis/armada"
+	"github.com/openshift-pipelines/tekton-armadas/pkg/seal"
+	"github.com/openshift-pipelines/tekton-armadas/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ktypes "k8s.io/apimachinery/pkg/types"
+)
+
+// openSecrets decrypts the Secrets of the event with the private key of the
+// minion, in the private-key.pem key of the ARMADA_ENCRYPTION_SECRET Secret.
+func (c *controller) openSecrets(aEvent types.ArmadaEvent) ([]*corev1.Secret, error) {
+	if len(aEvent.Secrets) == 0 {
+		return nil, nil
+	}
+	if c.encryptionSecret == "" {
+		return nil, fmt.Errorf("%w: the event has %d secrets and ARMADA_ENCRYPTION_SECRET is not set", errInvalidEvent, len(aEvent.Secrets))
+	}
+	privateKey, err := c.getSecretKey(c.encryptionSecret, seal.PrivateKeyKey)
+	if err != nil {
+		return nil, err
+	}
+
+	secrets := make([]*corev1.Secret, 0, len(aEvent.Secrets))
+	for _, sealed := range aEvent.Secrets {
+		data, err := seal.Open(privateKey, sealed)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", errInvalidEvent, err.Error())
+		}
+		secret := &corev1.Secret{}
+		if err := json.Unmarshal(data, secret); err != nil {
+			return nil, fmt.Errorf("%w: invalid secret %s: %s", errInvalidEvent, sealed.Name, err.Error())
+		}
+		if secret.GetName() != sealed.Name {
+			return nil, fmt.Errorf("%w: sealed secret %s holds secret %s", errInvalidEvent, sealed.Name, secret.GetName())
+		}
+		secrets = append(secrets, secret)
+	}
+	return secrets, nil
+}
+
+// runOwner returns the reference to the run created by the minion the
+// Secrets of the event are owned by. The owner does not block the deletion
+// of the Secrets, that would need the minion to update the finalizers of
+// the runs.
+func runOwner[T metav1.Object](ctx context.Context, client resourceClient[T], name string, gvk schema.GroupVersionKind) (metav1.OwnerReference, error) {
+	run, err := client.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return metav1.OwnerReference{}, err
+	}
+	return metav1.OwnerReference{
+		APIVersion: gvk.GroupVersion().String(),
+		Kind:       gvk.Kind,
+		Name:       run.GetName(),
+		UID:        run.GetUID(),
+	}, nil
+}
+
+// applySecrets creates the Secrets opened out of the event or updates the
+// ones applied by armada, adding the owner to their owners when set so they
+// are garbage collected once all the runs needing them are deleted.
+func (c *controller) applySecrets(ctx context.Context, namespace string, secrets []*corev1.Secret, owner *metav1.OwnerReference) error {
+	client := c.clients.Kube.CoreV1().Secrets(namespace)
+	for _, secret := range secrets {
+		secret = secret.DeepCopy()
+		setManaged(secret)
+		existing, err := client.Get(ctx, secret.GetName(), metav1.GetOptions{})
+		switch {
+		case errors.IsNotFound(err):
+			existing = nil
+		case err != nil:
+			return fmt.Errorf("error getting secret %s: %w", secret.GetName(), err)
+		case !isManaged(existing):
+			return fmt.Errorf("%w: secret %s already exists without the %s=%s label", errUnmanagedResource, secret.GetName(), armada.LabelManagedBy, armada.ManagedBy)
+		}
+
+		owners := []metav1.OwnerReference{}
+		if existing != nil {
+			owners = existing.GetOwnerReferences()
+		}
+		if owner != nil && !hasOwner(owners, owner.UID) {
+			owners = append(owners, *owner)
+		}
+		secret.SetOwnerReferences(owners)
+
+		if existing == nil {
+			_, err = client.Create(ctx, secret, metav1.CreateOptions{})
+		} else {
+			secret.SetResourceVersion(existing.GetResourceVersion())
+			_, err = client.Update(ctx, secret, metav1.UpdateOptions{})
+		}
+		if err != nil {
+			return fmt.Errorf("error applying secret %s: %w", secret.GetName(), err)
+		}
+		c.logger.Infof("secret %s has been applied", secret.GetName())
+	}
+	return nil
+}
+
+func hasOwner(owners []metav1.OwnerReference, uid ktypes.UID) bool {
+	for _, o := range owners {
+		if o.UID == uid {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,32 @@
+package minion
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/client-go/informers"
+)
+
+// startSecretInformer watches the Secrets and the ConfigMaps of the minion
+// namespace so rotated credentials and configuration changes are picked up
+// without restarting the minion.
+func (c *controller) startSecretInformer(ctx context.Context) {
+	factory := informers.NewSharedInformerFactoryWithOptions(c.clients.Kube, statusResyncPeriod, informers.WithNamespace(c.namespace))
+	c.secretLister = factory.Core().V1().Secrets().Lister()
+	c.configMapLister = factory.Core().V1().ConfigMaps().Lister()
+	factory.Start(ctx.Done())
+	factory.WaitForCacheSync(ctx.Done())
+}
+
+// getSecretKey returns the value of the key in the Secret of the minion namespace.
+func (c *controller) getSecretKey(name, key string) ([]byte, error) {
+	secret, err := c.secretLister.Secrets(c.namespace).Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get secret %s/%s: %w", c.namespace, name, err)
+	}
+	value, ok := secret.Data[key]
+	if !ok || len(value) == 0 {
+		return nil, fmt.Errorf("secret %s/%s has no %s key", c.namespace, name, key)
+	}
+	return value, nil
+}
@@ -0,0 +1,129 @@
+package minion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/openshift-pipelines/tekton-armadas/pkg/apis/armada"
+	"github.com/openshift-pipelines/tekton-armadas/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/kmeta"
+)
+
+// errServiceAccountNotFound is returned when a run uses a ServiceAccount
+// missing in the target namespace and the configuration does not create it.
+var errServiceAccountNotFound = errors.New("serviceaccount not found")
+
+// runServiceAccounts returns the ServiceAccounts the runs use, but the
+// default one which exists in every namespace.
+func runServiceAccounts(tt types.Types) []string {
+	names := []string{}
+	seen := map[string]bool{"": true, defaultServiceAccount: true}
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	for _, pr := range tt.Tekton.PipelineRuns {
+		add(pr.Spec.TaskRunTemplate.ServiceAccountName)
+		for _, spec := range pr.Spec.TaskRunSpecs {
+			add(spec.ServiceAccountName)
+		}
+	}
+	for _, tr := range tt.Tekton.TaskRuns {
+		add(tr.Spec.ServiceAccountName)
+	}
+	return names
+}
+
+// isImagePullSecret checks if the Secret holds registry credentials.
+func isImagePullSecret(secret *corev1.Secret) bool {
+	return secret.Type == corev1.SecretTypeDockerConfigJson || secret.Type == corev1.SecretTypeDockercfg
+}
+
+// ensureServiceAccounts checks the ServiceAccounts of the runs exist in the
+// namespace, creating the missing ones when the configuration allows it.
+// The created ServiceAccounts get the image pull secrets of the template and
+// the ones sent with the run.
+func (c *controller) ensureServiceAccounts(ctx context.Context, cfg *minionConfig, namespace string, tt types.Types, secrets []*corev1.Secret) error {
+	for _, name := range runServiceAccounts(tt) {
+		_, err := c.clients.Kube.CoreV1().ServiceAccounts(namespace).Get(ctx, name, metav1.GetOptions{})
+		switch {
+		case err == nil:
+			continue
+		case !apierrors.IsNotFound(err):
+			return fmt.Errorf("failed to get serviceaccount %s in namespace %s: %w", name, namespace, err)
+		case !cfg.CreateServiceAccounts:
+			return fmt.Errorf("%w: %s in namespace %s and %s is not enabled", errServiceAccountNotFound, name, namespace, serviceAccountCreateKey)
+		}
+
+		pullSecrets := append([]*corev1.Secret{}, tt.Kube.Secrets...)
+		if err := c.createServiceAccount(ctx, cfg, namespace, name, append(pullSecrets, secrets...)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createServiceAccount creates the ServiceAccount out of the template of the
+// configuration and binds it to its ClusterRole.
+func (c *controller) createServiceAccount(ctx context.Context, cfg *minionConfig, namespace, name string, secrets []*corev1.Secret) error {
+	sa := cfg.ServiceAccountTemplate.DeepCopy()
+	sa.ObjectMeta = metav1.ObjectMeta{
+		Name:        name,
+		Namespace:   namespace,
+		Labels:      sa.GetLabels(),
+		Annotations: sa.GetAnnotations(),
+	}
+	if sa.Labels == nil {
+		sa.Labels = map[string]string{}
+	}
+	sa.Labels[armada.LabelDispatched] = "true"
+	for _, secret := range secrets {
+		if isImagePullSecret(secret) {
+			sa.ImagePullSecrets = append(sa.ImagePullSecrets, corev1.LocalObjectReference{Name: secret.GetName()})
+		}
+	}
+
+	_, err := c.clients.Kube.CoreV1().ServiceAccounts(namespace).Create(ctx, sa, metav1.CreateOptions{})
+	switch {
+	case err == nil:
+		c.logger.Infof("serviceaccount %s has been created in namespace %s", name, namespace)
+	case !apierrors.IsAlreadyExists(err):
+		return fmt.Errorf("failed to create serviceaccount %s in namespace %s: %w", name, namespace, err)
+	}
+
+	if cfg.ServiceAccountClusterRole == "" {
+		return nil
+	}
+	rb := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      kmeta.ChildName(name, "-"+cfg.ServiceAccountClusterRole),
+			Namespace: namespace,
+			Labels:    map[string]string{armada.LabelDispatched: "true"},
+		},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "ClusterRole",
+			Name:     cfg.ServiceAccountClusterRole,
+		},
+		Subjects: []rbacv1.Subject{{
+			Kind:      rbacv1.ServiceAccountKind,
+			Name:      name,
+			Namespace: namespace,
+		}},
+	}
+	_, err = c.clients.Kube.RbacV1().RoleBindings(namespace).Create(ctx, rb, metav1.CreateOptions{})
+	switch {
+	case err == nil:
+		c.logger.Infof("serviceaccount %s has been bound to clusterrole %s in namespace %s", name, cfg.ServiceAccountClusterRole, namespace)
+	case !apierrors.IsAlreadyExists(err):
+		return fmt.Errorf("failed to bind serviceaccount %s to clusterrole %s in namespace %s: %w", name, cfg.ServiceAccountClusterRole, namespace, err)
+	}
+	return nil
+}
@@ -0,0 +1,314 @@
+package minion
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/openshift-pipelines/tekton-armadas/pkg/apis/armada"
+	"github.com/openshift-pipelines/tekton-armadas/pkg/types"
+	pipelineapi "github.com/tektoncd/pipeline/pkg/apis/pipeline"
+	tektonv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	tektonInformers "github.com/tektoncd/pipeline/pkg/client/informers/externalversions"
+	tektonListersv1 "github.com/tektoncd/pipeline/pkg/client/listers/pipeline/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	statusResyncPeriod     = 10 * time.Minute
+	statusReportMaxRetries = 10
+
+	// maxResultSize is the size of the largest result value reported, larger
+	// results are dropped.
+	maxResultSize = 4 * 1024
+	// maxTaskResultsSize is the total size of the task results reported for
+	// a PipelineRun, the results past it are dropped.
+	maxTaskResultsSize = 32 * 1024
+)
+
+// statusKey is a run whose status has to be reported, the PipelineRuns and
+// the TaskRuns not part of a PipelineRun.
+type statusKey struct {
+	kind string
+	key  string
+}
+
+// statusListers are the listers of the runs created by the minion.
+type statusListers struct {
+	pipelineRuns tektonListersv1.PipelineRunLister
+	taskRuns     tektonListersv1.TaskRunLister
+}
+
+// startStatusReporter watches the runs created by the minion and reports
+// their status to the orchestrator every time it changes, along with
+// the heartbeats of the minion.
+func (c *controller) startStatusReporter(ctx context.Context) error {
+	if c.sink == "" {
+		c.logger.Info("K_SINK is not set, the status of the PipelineRuns will not be reported to the orchestrator")
+		return nil
+	}
+	if c.minionName == "" {
+		c.logger.Warn("ARMADA_MINION_NAME is not set, the status of the PipelineRuns and the health of the minion will not be reported to the orchestrator")
+		return nil
+	}
+	if c.signatureSecret == "" {
+		c.logger.Warn("ARMADA_SIGNATURE_SECRET is not set, the orchestrator rejects the unsigned status and heartbeats")
+	}
+
+	factory := tektonInformers.NewSharedInformerFactoryWithOptions(c.clients.Tekton, statusResyncPeriod,
+		tektonInformers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.LabelSelector = armada.LabelDispatched + "=true"
+		}))
+	informer := factory.Tekton().V1().PipelineRuns()
+	// the child TaskRuns inherit the labels of the PipelineRun
+	taskRunInformer := factory.Tekton().V1().TaskRuns()
+	queue := workqueue.NewTypedRateLimitingQueueWithConfig(
+		workqueue.DefaultTypedControllerRateLimiter[statusKey](),
+		workqueue.TypedRateLimitingQueueConfig[statusKey]{Name: "minion-status"},
+	)
+
+	enqueue := func(kind string) func(obj interface{}) {
+		return func(obj interface{}) {
+			if key, err := cache.MetaNamespaceKeyFunc(obj); err == nil {
+				queue.Add(statusKey{kind: kind, key: key})
+			}
+		}
+	}
+	if _, err := informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    enqueue(pipelineapi.PipelineRunControllerName),
+		UpdateFunc: func(_, obj interface{}) { enqueue(pipelineapi.PipelineRunControllerName)(obj) },
+	}); err != nil {
+		return fmt.Errorf("failed to register PipelineRun informer event handler: %w", err)
+	}
+	// the TaskRuns of the PipelineRuns are reported with their PipelineRun
+	if _, err := taskRunInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: isStandaloneTaskRun,
+		Handler: cache.ResourceEventHandlerFuncs{
+			AddFunc:    enqueue(pipelineapi.TaskRunControllerName),
+			UpdateFunc: func(_, obj interface{}) { enqueue(pipelineapi.TaskRunControllerName)(obj) },
+		},
+	}); err != nil {
+		return fmt.Errorf("failed to register TaskRun informer event handler: %w", err)
+	}
+
+	factory.Start(ctx.Done())
+	factory.WaitForCacheSync(ctx.Done())
+
+	listers := statusListers{pipelineRuns: informer.Lister(), taskRuns: taskRunInformer.Lister()}
+	go func() {
+		<-ctx.Done()
+		queue.ShutDown()
+	}()
+	go func() {
+		for c.processNextStatus(ctx, queue, listers) {
+		}
+	}()
+
+	go c.startHeartbeat(ctx, listers)
+	return nil
+}
+
+// isStandaloneTaskRun checks if the object is a TaskRun not part of a
+// PipelineRun.
+func isStandaloneTaskRun(obj interface{}) bool {
+	tr, ok := obj.(*tektonv1.TaskRun)
+	if !ok {
+		return false
+	}
+	_, child := tr.GetLabels()[pipelineapi.PipelineRunLabelKey]
+	return !child
+}
+
+func (c *controller) processNextStatus(ctx context.Context, queue workqueue.TypedRateLimitingInterface[statusKey], listers statusListers) bool {
+	key, quit := queue.Get()
+	if quit {
+		return false
+	}
+	defer queue.Done(key)
+
+	var err error
+	if key.kind == pipelineapi.TaskRunControllerName {
+		err = c.reportTaskRunStatus(ctx, listers, key.key)
+	} else {
+		err = c.reportStatus(ctx, listers, key.key)
+	}
+	switch {
+	case err == nil:
+		queue.Forget(key)
+	case queue.NumRequeues(key) < statusReportMaxRetries:
+		c.logger.Warnf("failed to report status of %s %s, retrying: %v", key.kind, key.key, err)
+		queue.AddRateLimited(key)
+	default:
+		c.logger.Errorf("failed to report status of %s %s, giving up: %v", key.kind, key.key, err)
+		queue.Forget(key)
+	}
+	return true
+}
+
+// resultSize returns the size of the value of a result.
+func resultSize(value tektonv1.ResultValue) int {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return 0
+	}
+	return len(data)
+}
+
+// pipelineResults returns the results of the PipelineRun not larger than
+// maxResultSize.
+func (c *controller) pipelineResults(pr *tektonv1.PipelineRun) []tektonv1.PipelineRunResult {
+	results := []tektonv1.PipelineRunResult{}
+	for _, result := range pr.Status.Results {
+		if resultSize(result.Value) > maxResultSize {
+			c.logger.Warnf("result %s of pipelinerun %s is larger than %d bytes, not reporting it", result.Name, pr.GetName(), maxResultSize)
+			continue
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// taskResults returns the results of the TaskRuns of the PipelineRun by
+// pipeline task, dropping the results larger than maxResultSize and the ones
+// past maxTaskResultsSize.
+func (c *controller) taskResults(pr *tektonv1.PipelineRun, trs []*tektonv1.TaskRun) map[string]map[string]tektonv1.ResultValue {
+	sort.Slice(trs, func(i, j int) bool { return trs[i].GetName() < trs[j].GetName() })
+	total := 0
+	taskResults := map[string]map[string]tektonv1.ResultValue{}
+	for _, tr := range trs {
+		task := tr.GetLabels()[pipelineapi.PipelineTaskLabelKey]
+		for _, result := range tr.Status.Results {
+			size := resultSize(result.Value)
+			if size > maxResultSize || total+size > maxTaskResultsSize {
+				c.logger.Warnf("result %s of task %s of pipelinerun %s is over the size limits, not reporting it", result.Name, task, pr.GetName())
+				continue
+			}
+			total += size
+			if taskResults[task] == nil {
+				taskResults[task] = map[string]tektonv1.ResultValue{}
+			}
+			taskResults[task][result.Name] = result.Value
+		}
+	}
+	return taskResults
+}
+
+// statusEvent builds the status event of the PipelineRun with only the
+// fields mirrored on the source PipelineRun.
+func (c *controller) statusEvent(pr *tektonv1.PipelineRun, trs []*tektonv1.TaskRun) types.ArmadaStatusEvent {
+	se := types.ArmadaStatusEvent{
+		Minion:          c.minionName,
+		Namespace:       pr.GetAnnotations()[armada.AnnotationSourceNamespace],
+		Name:            pr.GetAnnotations()[armada.AnnotationSourceName],
+		RemoteNamespace: pr.GetNamespace(),
+		RemoteName:      pr.GetName(),
+		TaskResults:     c.taskResults(pr, trs),
+	}
+	se.Status.Conditions = pr.Status.Conditions
+	se.Status.StartTime = pr.Status.StartTime
+	se.Status.CompletionTime = pr.Status.CompletionTime
+	se.Status.ChildReferences = pr.Status.ChildReferences
+	se.Status.Results = c.pipelineResults(pr)
+	return se
+}
+
+func (c *controller) reportStatus(ctx context.Context, listers statusListers, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return nil
+	}
+	pr, err := listers.pipelineRuns.PipelineRuns(namespace).Get(name)
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	trs, err := listers.taskRuns.TaskRuns(namespace).List(labels.SelectorFromSet(labels.Set{pipelineapi.PipelineRunLabelKey: name}))
+	if err != nil {
+		return err
+	}
+
+	se := c.statusEvent(pr, trs)
+	if se.Namespace == "" || se.Name == "" {
+		c.logger.Debugf("pipelinerun %s has no source, not reporting its status", key)
+		return nil
+	}
+
+	// signed like the heartbeats, the orchestrator only takes the status of
+	// a run from the minion it has been dispatched to
+	response, err := c.sendToOrchestrator(ctx, types.EventTypeStatus, se)
+	if err != nil {
+		return fmt.Errorf("failed to send status cloudevent: %w", err)
+	}
+	if err := response.Body.Close(); err != nil {
+		return err
+	}
+	c.logger.Debugf("status of pipelinerun %s has been reported to the orchestrator", key)
+	return nil
+}
+
+// taskRunResults returns the results of the TaskRun not larger than
+// maxResultSize.
+func (c *controller) taskRunResults(tr *tektonv1.TaskRun) []tektonv1.TaskRunResult {
+	results := []tektonv1.TaskRunResult{}
+	for _, result := range tr.Status.Results {
+		if resultSize(result.Value) > maxResultSize {
+			c.logger.Warnf("result %s of taskrun %s is larger than %d bytes, not reporting it", result.Name, tr.GetName(), maxResultSize)
+			continue
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// taskRunStatusEvent builds the status event of the TaskRun with only the
+// fields mirrored on the source TaskRun.
+func (c *controller) taskRunStatusEvent(tr *tektonv1.TaskRun) types.ArmadaStatusEvent {
+	status := &tektonv1.TaskRunStatus{}
+	status.Conditions = tr.Status.Conditions
+	status.StartTime = tr.Status.StartTime
+	status.CompletionTime = tr.Status.CompletionTime
+	status.Results = c.taskRunResults(tr)
+	return types.ArmadaStatusEvent{
+		Minion:          c.minionName,
+		Namespace:       tr.GetAnnotations()[armada.AnnotationSourceNamespace],
+		Name:            tr.GetAnnotations()[armada.AnnotationSourceName],
+		RemoteNamespace: tr.GetNamespace(),
+		RemoteName:      tr.GetName(),
+		TaskRunStatus:   status,
+	}
+}
+
+func (c *controller) reportTaskRunStatus(ctx context.Context, listers statusListers, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return nil
+	}
+	tr, err := listers.taskRuns.TaskRuns(namespace).Get(name)
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	se := c.taskRunStatusEvent(tr)
+	if se.Namespace == "" || se.Name == "" {
+		c.logger.Debugf("taskrun %s has no source, not reporting its status", key)
+		return nil
+	}
+	response, err := c.sendToOrchestrator(ctx, types.EventTypeStatus, se)
+	if err != nil {
+		return fmt.Errorf("failed to send status cloudevent: %w", err)
+	}
+	if err := response.Body.Close(); err != nil {
+		return err
+	}
+	c.logger.Debugf("status of taskrun %s has been reported to the orchestrator", key)
+	return nil
+}
@@ -0,0 +1,85 @@
+package minion
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// caCertKey is the key of the CA certificate in a Secret.
+const caCertKey = "ca.crt"
+
+// tlsConfigLoader builds the TLS configuration of the minion server out of
+// the Secrets, the configuration is rebuilt when the Secrets are rotated.
+type tlsConfigLoader struct {
+	c *controller
+	// certSecret holds the serving certificate and key.
+	certSecret string
+	// clientCASecret holds the CA the client certificates of the orchestrator
+	// are verified against, client certificates are not required when empty.
+	clientCASecret string
+
+	mu       sync.Mutex
+	versions string
+	config   *tls.Config
+}
+
+func (l *tlsConfigLoader) getConfigForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	certSecret, err := l.c.secretLister.Secrets(l.c.namespace).Get(l.certSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tls secret %s: %w", l.certSecret, err)
+	}
+	var caSecret *corev1.Secret
+	versions := certSecret.GetResourceVersion()
+	if l.clientCASecret != "" {
+		if caSecret, err = l.c.secretLister.Secrets(l.c.namespace).Get(l.clientCASecret); err != nil {
+			return nil, fmt.Errorf("failed to get client ca secret %s: %w", l.clientCASecret, err)
+		}
+		versions += "/" + caSecret.GetResourceVersion()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.config != nil && l.versions == versions {
+		return l.config, nil
+	}
+
+	cert, err := tls.X509KeyPair(certSecret.Data[corev1.TLSCertKey], certSecret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certificate from secret %s: %w", l.certSecret, err)
+	}
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+	if caSecret != nil {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caSecret.Data[caCertKey]) {
+			return nil, fmt.Errorf("no valid %s in client ca secret %s", caCertKey, l.clientCASecret)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	l.c.logger.Infof("loaded tls configuration from secrets %s", versions)
+	l.config, l.versions = config, versions
+	return config, nil
+}
+
+// tlsConfig returns the configuration of the server.
+func (l *tlsConfigLoader) tlsConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		GetConfigForClient: l.getConfigForClient,
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			config, err := l.getConfigForClient(hello)
+			if err != nil {
+				return nil, err
+			}
+			return &config.Certificates[0], nil
+		},
+	}
+}
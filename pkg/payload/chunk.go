@@ -0,0 +1,208 @@
+package payload
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/openshift-pipelines/tekton-armadas/pkg/types"
+)
+
+const (
+	// ContentTypeChunk is the content type of the data of the chunks.
+	ContentTypeChunk = "application/octet-stream"
+
+	// ExtensionChunkID is the extension attribute with the ID of the event
+	// the chunk is part of.
+	ExtensionChunkID = "armadachunkid"
+	// ExtensionChunkIndex is the extension attribute with the position of
+	// the chunk, from 0.
+	ExtensionChunkIndex = "armadachunkindex"
+	// ExtensionChunkCount is the extension attribute with the number of
+	// chunks of the event.
+	ExtensionChunkCount = "armadachunkcount"
+	// ExtensionDigest is the extension attribute with the sha256 digest of
+	// the data of the whole event.
+	ExtensionDigest = "armadadigest"
+	// ExtensionEventType is the extension attribute with the type of the
+	// event the chunk is part of.
+	ExtensionEventType = "armadaeventtype"
+	// ExtensionContentType is the extension attribute with the content type
+	// of the event the chunk is part of, the chunks are opaque bytes.
+	ExtensionContentType = "armadacontenttype"
+
+	// MinChunkSize is the size of the smallest chunk, it bounds the number of
+	// chunks of an event.
+	MinChunkSize = 16 * 1024
+
+	digestPrefix = "sha256:"
+)
+
+var ErrInvalidChunk = errors.New("invalid chunk")
+
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return digestPrefix + hex.EncodeToString(sum[:])
+}
+
+// Split returns the chunks of the event with at most chunkSize bytes of
+// data each, the event itself when it is small enough or chunkSize is zero.
+func Split(event cloudevents.Event, chunkSize int) ([]cloudevents.Event, error) {
+	data := event.Data()
+	if chunkSize <= 0 || len(data) <= chunkSize {
+		return []cloudevents.Event{event}, nil
+	}
+
+	count := (len(data) + chunkSize - 1) / chunkSize
+	sum := digest(data)
+	chunks := make([]cloudevents.Event, 0, count)
+	for i := 0; i < count; i++ {
+		end := min((i+1)*chunkSize, len(data))
+		chunk := cloudevents.NewEvent()
+		chunk.SetSource(event.Source())
+		chunk.SetType(types.EventTypeChunk)
+		chunk.SetID(fmt.Sprintf("%s-%d", event.ID(), i))
+		for name, value := range event.Extensions() {
+			chunk.SetExtension(name, value)
+		}
+		chunk.SetExtension(ExtensionChunkID, event.ID())
+		chunk.SetExtension(ExtensionChunkIndex, strconv.Itoa(i))
+		chunk.SetExtension(ExtensionChunkCount, strconv.Itoa(count))
+		chunk.SetExtension(ExtensionDigest, sum)
+		chunk.SetExtension(ExtensionEventType, event.Type())
+		chunk.SetExtension(ExtensionContentType, event.DataContentType())
+		if err := chunk.SetData(ContentTypeChunk, data[i*chunkSize:end]); err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks, nil
+}
+
+// partialEvent is an event of which some chunks have been received.
+type partialEvent struct {
+	chunks   [][]byte
+	received int
+	size     int64
+	timer    *time.Timer
+}
+
+// Assembler reassembles the events split in chunks.
+type Assembler struct {
+	mu       sync.Mutex
+	partial  map[string]*partialEvent
+	buffered int64
+	// MaxSize is the size of the largest event reassembled.
+	MaxSize int64
+	// MaxBuffered is the size of the chunks kept across all the incomplete
+	// events, the chunks past it are rejected.
+	MaxBuffered int64
+	// TTL is how long the chunks of an incomplete event are kept.
+	TTL time.Duration
+}
+
+func NewAssembler(maxSize, maxBuffered int64, ttl time.Duration) *Assembler {
+	return &Assembler{partial: map[string]*partialEvent{}, MaxSize: maxSize, MaxBuffered: maxBuffered, TTL: ttl}
+}
+
+// maxChunks is the number of chunks of the largest event reassembled.
+func (a *Assembler) maxChunks() int {
+	return int(max((a.MaxSize+MinChunkSize-1)/MinChunkSize, 1))
+}
+
+// drop forgets the chunks of the event, the caller holds the lock.
+func (a *Assembler) drop(id string, p *partialEvent) {
+	if a.partial[id] != p {
+		return
+	}
+	p.timer.Stop()
+	a.buffered -= p.size
+	delete(a.partial, id)
+}
+
+// expire drops the chunks of the event once it has not been completed in time.
+func (a *Assembler) expire(id string, p *partialEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.drop(id, p)
+}
+
+// Add records the chunk and returns the whole event once all its chunks have
+// been received and its digest verified, nil until then.
+func (a *Assembler) Add(chunk cloudevents.Event) (*cloudevents.Event, error) {
+	id := extension(chunk, ExtensionChunkID)
+	index, errIndex := strconv.Atoi(extension(chunk, ExtensionChunkIndex))
+	count, errCount := strconv.Atoi(extension(chunk, ExtensionChunkCount))
+	if id == "" || errIndex != nil || errCount != nil || count <= 0 || index < 0 || index >= count {
+		return nil, fmt.Errorf("%w: missing or invalid chunk attributes", ErrInvalidChunk)
+	}
+	if count > a.maxChunks() {
+		return nil, fmt.Errorf("%w: event %s has %d chunks, over the limit of %d chunks", ErrTooLarge, id, count, a.maxChunks())
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	p, ok := a.partial[id]
+	if !ok {
+		p = &partialEvent{chunks: make([][]byte, count)}
+		p.timer = time.AfterFunc(a.TTL, func() { a.expire(id, p) })
+		a.partial[id] = p
+	}
+	if len(p.chunks) != count {
+		a.drop(id, p)
+		return nil, fmt.Errorf("%w: chunk count of event %s changed", ErrInvalidChunk, id)
+	}
+
+	data := chunk.Data()
+	growth := int64(len(data)) - int64(len(p.chunks[index]))
+	if p.size+growth > a.MaxSize {
+		a.drop(id, p)
+		return nil, fmt.Errorf("%w: event %s is over the limit of %d bytes", ErrTooLarge, id, a.MaxSize)
+	}
+	if a.buffered+growth > a.MaxBuffered {
+		if p.received == 0 {
+			a.drop(id, p)
+		}
+		return nil, fmt.Errorf("%w: %d bytes of chunks are already waiting for their event", ErrTooLarge, a.buffered)
+	}
+	if p.chunks[index] == nil {
+		p.received++
+	}
+	p.chunks[index] = data
+	p.size += growth
+	a.buffered += growth
+	if p.received < count {
+		return nil, nil
+	}
+	size := p.size
+	a.drop(id, p)
+
+	data = make([]byte, 0, size)
+	for _, chunkData := range p.chunks {
+		data = append(data, chunkData...)
+	}
+	if sum := digest(data); sum != extension(chunk, ExtensionDigest) {
+		return nil, fmt.Errorf("%w: digest of event %s does not match", ErrInvalidChunk, id)
+	}
+
+	event := cloudevents.NewEvent()
+	event.SetSource(chunk.Source())
+	event.SetType(extension(chunk, ExtensionEventType))
+	event.SetID(id)
+	for name, value := range chunk.Extensions() {
+		switch name {
+		case ExtensionChunkID, ExtensionChunkIndex, ExtensionChunkCount, ExtensionDigest, ExtensionEventType, ExtensionContentType:
+		default:
+			event.SetExtension(name, value)
+		}
+	}
+	if err := event.SetData(extension(chunk, ExtensionContentType), data); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
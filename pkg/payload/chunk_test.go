@@ -0,0 +1,272 @@
+package payload
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/openshift-pipelines/tekton-armadas/pkg/types"
+)
+
+// split returns the chunks of an event with size bytes of JSON data.
+func split(t *testing.T, id string, size, chunkSize int) (cloudevents.Event, []cloudevents.Event) {
+	t.Helper()
+	data := append(append([]byte(`"`), bytes.Repeat([]byte("a"), size-2)...), '"')
+	event := newEvent(t, data)
+	event.SetID(id)
+	event.SetExtension(ExtensionEncoding, EncodingGzip)
+	chunks, err := Split(event, chunkSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return event, chunks
+}
+
+// waiting returns the number of events and bytes waiting for their chunks.
+func (a *Assembler) waiting() (int, int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.partial), a.buffered
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name      string
+		size      int
+		chunkSize int
+		want      int
+	}{
+		{name: "chunking disabled", size: 100, chunkSize: 0, want: 1},
+		{name: "smaller than a chunk", size: 100, chunkSize: 100, want: 1},
+		{name: "exact chunks", size: 300, chunkSize: 100, want: 3},
+		{name: "last chunk shorter", size: 301, chunkSize: 100, want: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, chunks := split(t, "id", tt.size, tt.chunkSize)
+			if len(chunks) != tt.want {
+				t.Fatalf("Split() = %d chunks, want %d", len(chunks), tt.want)
+			}
+			if tt.want == 1 {
+				if chunks[0].Type() != event.Type() || !bytes.Equal(chunks[0].Data(), event.Data()) {
+					t.Errorf("Split() = %v, want the event itself", chunks[0])
+				}
+				return
+			}
+			for i, chunk := range chunks {
+				if chunk.Type() != types.EventTypeChunk {
+					t.Errorf("chunk %d type = %s, want %s", i, chunk.Type(), types.EventTypeChunk)
+				}
+				if got := extension(chunk, ExtensionChunkIndex); got != strconv.Itoa(i) {
+					t.Errorf("chunk %d index = %s", i, got)
+				}
+				if got := extension(chunk, ExtensionChunkCount); got != strconv.Itoa(tt.want) {
+					t.Errorf("chunk %d count = %s, want %d", i, got, tt.want)
+				}
+				if got := extension(chunk, ExtensionEventType); got != event.Type() {
+					t.Errorf("chunk %d event type = %s, want %s", i, got, event.Type())
+				}
+				if got := extension(chunk, ExtensionEncoding); got != EncodingGzip {
+					t.Errorf("chunk %d encoding = %s, want the extensions of the event", i, got)
+				}
+				if len(chunk.Data()) > tt.chunkSize {
+					t.Errorf("chunk %d has %d bytes, over %d", i, len(chunk.Data()), tt.chunkSize)
+				}
+			}
+		})
+	}
+}
+
+func TestAssemble(t *testing.T) {
+	tests := []struct {
+		name  string
+		order []int
+	}{
+		{name: "in order", order: []int{0, 1, 2, 3}},
+		{name: "out of order", order: []int{3, 1, 0, 2}},
+		{name: "duplicated chunk", order: []int{0, 1, 1, 2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, chunks := split(t, "id", 4*MinChunkSize, MinChunkSize)
+			a := NewAssembler(8*MinChunkSize, 8*MinChunkSize, time.Minute)
+			var assembled *cloudevents.Event
+			for i, index := range tt.order {
+				got, err := a.Add(chunks[index])
+				if err != nil {
+					t.Fatalf("Add(%d) = %v", index, err)
+				}
+				if i < len(tt.order)-1 && got != nil {
+					t.Fatalf("Add(%d) returned the event before all its chunks", index)
+				}
+				assembled = got
+			}
+			if assembled == nil {
+				t.Fatal("Add() did not return the event once all its chunks are received")
+			}
+			if assembled.ID() != event.ID() || assembled.Type() != event.Type() || assembled.DataContentType() != event.DataContentType() {
+				t.Errorf("Add() = %s %s %s, want %s %s %s", assembled.ID(), assembled.Type(), assembled.DataContentType(), event.ID(), event.Type(), event.DataContentType())
+			}
+			if !bytes.Equal(assembled.Data(), event.Data()) {
+				t.Error("the data of the event reassembled does not match")
+			}
+			if got := extension(*assembled, ExtensionEncoding); got != EncodingGzip {
+				t.Errorf("encoding = %s, want the extensions of the event", got)
+			}
+			if got := extension(*assembled, ExtensionChunkID); got != "" {
+				t.Errorf("chunk id = %s, want the chunk extensions removed", got)
+			}
+			if events, size := a.waiting(); events != 0 || size != 0 {
+				t.Errorf("%d events and %d bytes are still waiting", events, size)
+			}
+		})
+	}
+}
+
+func TestAssembleInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		tamper  func(chunks []cloudevents.Event)
+		wantErr error
+	}{
+		{
+			name: "missing id",
+			tamper: func(chunks []cloudevents.Event) {
+				_ = chunks[0].Context.SetExtension(ExtensionChunkID, nil)
+			},
+			wantErr: ErrInvalidChunk,
+		},
+		{
+			name: "index out of range",
+			tamper: func(chunks []cloudevents.Event) {
+				chunks[0].SetExtension(ExtensionChunkIndex, "4")
+			},
+			wantErr: ErrInvalidChunk,
+		},
+		{
+			name: "negative index",
+			tamper: func(chunks []cloudevents.Event) {
+				chunks[0].SetExtension(ExtensionChunkIndex, "-1")
+			},
+			wantErr: ErrInvalidChunk,
+		},
+		{
+			name: "chunk count changed",
+			tamper: func(chunks []cloudevents.Event) {
+				chunks[1].SetExtension(ExtensionChunkCount, "5")
+			},
+			wantErr: ErrInvalidChunk,
+		},
+		{
+			name: "tampered data",
+			tamper: func(chunks []cloudevents.Event) {
+				_ = chunks[3].SetData(ContentTypeChunk, []byte("tampered"))
+			},
+			wantErr: ErrInvalidChunk,
+		},
+		{
+			name: "tampered digest",
+			tamper: func(chunks []cloudevents.Event) {
+				chunks[3].SetExtension(ExtensionDigest, digest([]byte("another")))
+			},
+			wantErr: ErrInvalidChunk,
+		},
+		{
+			name: "too many chunks",
+			tamper: func(chunks []cloudevents.Event) {
+				chunks[0].SetExtension(ExtensionChunkCount, "1000")
+			},
+			wantErr: ErrTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, chunks := split(t, "id", 4*MinChunkSize, MinChunkSize)
+			tt.tamper(chunks)
+			a := NewAssembler(8*MinChunkSize, 8*MinChunkSize, time.Minute)
+			var err error
+			for _, chunk := range chunks {
+				if _, err = a.Add(chunk); err != nil {
+					break
+				}
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Add() = %v, want %v", err, tt.wantErr)
+			}
+			if events, size := a.waiting(); events != 0 || size != 0 {
+				t.Errorf("%d events and %d bytes are still waiting", events, size)
+			}
+		})
+	}
+}
+
+func TestAssembleLimits(t *testing.T) {
+	t.Run("event over the max size", func(t *testing.T) {
+		_, chunks := split(t, "id", 4*MinChunkSize, MinChunkSize)
+		// small enough in chunks, not in bytes
+		a := NewAssembler(3*MinChunkSize+MinChunkSize/2, 8*MinChunkSize, time.Minute)
+		var err error
+		for _, chunk := range chunks {
+			if _, err = a.Add(chunk); err != nil {
+				break
+			}
+		}
+		if !errors.Is(err, ErrTooLarge) {
+			t.Errorf("Add() = %v, want %v", err, ErrTooLarge)
+		}
+		if events, size := a.waiting(); events != 0 || size != 0 {
+			t.Errorf("%d events and %d bytes are still waiting", events, size)
+		}
+	})
+
+	t.Run("chunks over the max buffered", func(t *testing.T) {
+		_, first := split(t, "first", 4*MinChunkSize, MinChunkSize)
+		_, second := split(t, "second", 4*MinChunkSize, MinChunkSize)
+		a := NewAssembler(4*MinChunkSize, 3*MinChunkSize, time.Minute)
+		for _, chunk := range first[:3] {
+			if _, err := a.Add(chunk); err != nil {
+				t.Fatalf("Add() = %v", err)
+			}
+		}
+		if _, err := a.Add(second[0]); !errors.Is(err, ErrTooLarge) {
+			t.Errorf("Add() = %v, want %v", err, ErrTooLarge)
+		}
+		// the rejected event is not kept, the first one can still complete
+		if events, size := a.waiting(); events != 1 || size != 3*MinChunkSize {
+			t.Errorf("%d events and %d bytes are waiting, want 1 and %d", events, size, 3*MinChunkSize)
+		}
+	})
+}
+
+func TestAssembleExpire(t *testing.T) {
+	_, chunks := split(t, "id", 4*MinChunkSize, MinChunkSize)
+	a := NewAssembler(8*MinChunkSize, 8*MinChunkSize, 10*time.Millisecond)
+	if _, err := a.Add(chunks[0]); err != nil {
+		t.Fatalf("Add() = %v", err)
+	}
+	if events, _ := a.waiting(); events != 1 {
+		t.Fatalf("%d events are waiting, want 1", events)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		events, size := a.waiting()
+		if events == 0 && size == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d events and %d bytes are still waiting past the TTL", events, size)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// the chunks received after the expiration start the event over
+	for _, chunk := range chunks[1:] {
+		if got, err := a.Add(chunk); err != nil || got != nil {
+			t.Fatalf("Add() = %v, %v, want the event to wait for its first chunk", got, err)
+		}
+	}
+}
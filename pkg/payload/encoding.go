@@ -0,0 +1,142 @@
+// Package payload compresses the data of the events exchanged between the
+// orchestrator and the minions and splits the largest ones in chunks.
+package payload
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	cetypes "github.com/cloudevents/sdk-go/v2/types"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// ExtensionEncoding is the extension attribute with the encoding the data
+	// of the event has been compressed with.
+	ExtensionEncoding = "contentencoding"
+
+	EncodingNone = "none"
+	EncodingGzip = "gzip"
+	EncodingZstd = "zstd"
+
+	// ContentTypeCompressed is the content type of the compressed data.
+	ContentTypeCompressed = "application/octet-stream"
+)
+
+var (
+	ErrUnsupportedEncoding = errors.New("unsupported content encoding")
+	ErrTooLarge            = errors.New("payload too large")
+)
+
+// ValidEncoding checks if the data can be compressed with the encoding.
+func ValidEncoding(encoding string) bool {
+	return encoding == EncodingNone || encoding == EncodingGzip || encoding == EncodingZstd
+}
+
+func compress(encoding string, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case EncodingGzip:
+		w = gzip.NewWriter(&buf)
+	case EncodingZstd:
+		zw, err := zstd.NewWriter(&buf)
+		if err != nil {
+			return nil, err
+		}
+		w = zw
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnsupportedEncoding, encoding)
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompress returns the decompressed data, ErrTooLarge when it is over
+// maxSize.
+func decompress(encoding string, data []byte, maxSize int64) ([]byte, error) {
+	var r io.Reader
+	switch encoding {
+	case EncodingGzip:
+		gr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer gr.Close()
+		r = gr
+	case EncodingZstd:
+		zr, err := zstd.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnsupportedEncoding, encoding)
+	}
+	decompressed, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(decompressed)) > maxSize {
+		return nil, fmt.Errorf("%w: decompressed data over %d bytes", ErrTooLarge, maxSize)
+	}
+	return decompressed, nil
+}
+
+// extension returns the value of the extension attribute of the event as a
+// string, empty when it is not set.
+func extension(event cloudevents.Event, name string) string {
+	value, err := event.Context.GetExtension(name)
+	if err != nil {
+		return ""
+	}
+	s, err := cetypes.ToString(value)
+	if err != nil {
+		return ""
+	}
+	return s
+}
+
+// Compress compresses the data of the event with the encoding, recorded in
+// the content encoding extension attribute.
+func Compress(event *cloudevents.Event, encoding string) error {
+	if encoding == "" || encoding == EncodingNone {
+		return nil
+	}
+	compressed, err := compress(encoding, event.Data())
+	if err != nil {
+		return fmt.Errorf("failed to compress event data: %w", err)
+	}
+	if err := event.SetData(ContentTypeCompressed, compressed); err != nil {
+		return err
+	}
+	event.SetExtension(ExtensionEncoding, encoding)
+	return nil
+}
+
+// Decompress restores the JSON data of an event compressed with Compress,
+// the events which are not compressed are left as they are.
+func Decompress(event *cloudevents.Event, maxSize int64) error {
+	encoding := extension(*event, ExtensionEncoding)
+	if encoding == "" || encoding == EncodingNone {
+		return nil
+	}
+	data, err := decompress(encoding, event.Data(), maxSize)
+	if err != nil {
+		return fmt.Errorf("failed to decompress event data: %w", err)
+	}
+	if err := event.SetData(cloudevents.ApplicationJSON, data); err != nil {
+		return err
+	}
+	return event.Context.SetExtension(ExtensionEncoding, nil)
+}
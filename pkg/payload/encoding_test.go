@@ -0,0 +1,165 @@
+package payload
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+)
+
+// newEvent returns an event with the JSON data.
+func newEvent(t *testing.T, data []byte) cloudevents.Event {
+	t.Helper()
+	event := cloudevents.NewEvent()
+	event.SetID("id")
+	event.SetSource("armada")
+	event.SetType("armada.tekton.dev/v1")
+	if err := event.SetData(cloudevents.ApplicationJSON, data); err != nil {
+		t.Fatal(err)
+	}
+	return event
+}
+
+func TestCompress(t *testing.T) {
+	data := []byte(`{"pipelineRun":"` + string(bytes.Repeat([]byte("a"), 4096)) + `"}`)
+
+	tests := []struct {
+		name       string
+		encoding   string
+		maxSize    int64
+		compressed bool
+		wantErr    error
+	}{
+		{
+			name:     "none",
+			encoding: EncodingNone,
+			maxSize:  int64(len(data)),
+		},
+		{
+			name:     "empty",
+			encoding: "",
+			maxSize:  int64(len(data)),
+		},
+		{
+			name:       "gzip",
+			encoding:   EncodingGzip,
+			maxSize:    int64(len(data)),
+			compressed: true,
+		},
+		{
+			name:       "zstd",
+			encoding:   EncodingZstd,
+			maxSize:    int64(len(data)),
+			compressed: true,
+		},
+		{
+			name:     "unsupported encoding",
+			encoding: "brotli",
+			maxSize:  int64(len(data)),
+			wantErr:  ErrUnsupportedEncoding,
+		},
+		{
+			name:       "gzip over the limit once decompressed",
+			encoding:   EncodingGzip,
+			maxSize:    int64(len(data)) - 1,
+			compressed: true,
+			wantErr:    ErrTooLarge,
+		},
+		{
+			name:       "zstd over the limit once decompressed",
+			encoding:   EncodingZstd,
+			maxSize:    int64(len(data)) - 1,
+			compressed: true,
+			wantErr:    ErrTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := newEvent(t, data)
+			err := Compress(&event, tt.encoding)
+			if !tt.compressed {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Compress() = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Compress() = %v", err)
+			}
+			if len(event.Data()) >= len(data) {
+				t.Errorf("compressed data is %d bytes, want less than %d", len(event.Data()), len(data))
+			}
+			if got := extension(event, ExtensionEncoding); got != tt.encoding {
+				t.Errorf("encoding = %q, want %q", got, tt.encoding)
+			}
+
+			err = Decompress(&event, tt.maxSize)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Decompress() = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !bytes.Equal(event.Data(), data) {
+				t.Errorf("Decompress() = %q, want %q", event.Data(), data)
+			}
+			if event.DataContentType() != cloudevents.ApplicationJSON {
+				t.Errorf("content type = %q, want %q", event.DataContentType(), cloudevents.ApplicationJSON)
+			}
+			if got := extension(event, ExtensionEncoding); got != "" {
+				t.Errorf("encoding = %q once decompressed, want none", got)
+			}
+		})
+	}
+}
+
+func TestDecompress(t *testing.T) {
+	data := []byte(`{"name":"run"}`)
+
+	tests := []struct {
+		name     string
+		encoding string
+		wantErr  error
+	}{
+		{
+			name: "not compressed",
+		},
+		{
+			name:     "not compressed with the none encoding",
+			encoding: EncodingNone,
+		},
+		{
+			name:     "unsupported encoding",
+			encoding: "brotli",
+			wantErr:  ErrUnsupportedEncoding,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := newEvent(t, data)
+			if tt.encoding != "" {
+				event.SetExtension(ExtensionEncoding, tt.encoding)
+			}
+			err := Decompress(&event, 1024)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Decompress() = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && !bytes.Equal(event.Data(), data) {
+				t.Errorf("Decompress() = %q, want %q", event.Data(), data)
+			}
+		})
+	}
+}
+
+func TestDecompressCorrupted(t *testing.T) {
+	for _, encoding := range []string{EncodingGzip, EncodingZstd} {
+		t.Run(encoding, func(t *testing.T) {
+			event := newEvent(t, []byte(`{"name":"run"}`))
+			event.SetExtension(ExtensionEncoding, encoding)
+			if err := Decompress(&event, 1024); err == nil {
+				t.Errorf("Decompress() = nil, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+
+	atypes "github.com/openshift-pipelines/tekton-armadas/pkg/types"
+	pipelineapi "github.com/tektoncd/pipeline/pkg/apis/pipeline"
+	tektonv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/logging"
+)
+
+// isLocalTaskRef checks if the TaskRef references a Task of the namespace
+// rather than a remote resolver or a custom task.
+func isLocalTaskRef(ref *tektonv1.TaskRef) bool {
+	return ref != nil && ref.Name != "" && ref.Resolver == "" &&
+		(ref.Kind == "" || ref.Kind == tektonv1.NamespacedTaskKind) && ref.APIVersion == ""
+}
+
+// bundleTask returns the serialized Task of the namespace.
+func (r *Reconciler) bundleTask(ctx context.Context, namespace, name string) (string, error) {
+	task, err := r.clients.Tekton.TektonV1().Tasks(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	task.Kind = pipelineapi.TaskControllerName
+	task.APIVersion = tektonv1.SchemeGroupVersion.String()
+	return atypes.SerializeObjectYaml(task)
+}
+
+// bundleTasks returns the serialized Tasks of the namespace referenced by
+// the pipeline tasks, each Task only once.
+func (r *Reconciler) bundleTasks(ctx context.Context, namespace string, spec *tektonv1.PipelineSpec, seen map[string]bool) ([]string, error) {
+	bundled := []string{}
+	for _, pt := range append(append([]tektonv1.PipelineTask{}, spec.Tasks...), spec.Finally...) {
+		if !isLocalTaskRef(pt.TaskRef) || seen[pt.TaskRef.Name] {
+			continue
+		}
+		seen[pt.TaskRef.Name] = true
+		data, err := r.bundleTask(ctx, namespace, pt.TaskRef.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get task %s referenced by pipeline task %s: %w", pt.TaskRef.Name, pt.Name, err)
+		}
+		bundled = append(bundled, data)
+	}
+	return bundled, nil
+}
+
+// bundleWorkspaces returns the serialized ConfigMaps bound to the workspaces
+// of the run that opted in to be sent to the minion, the Secrets are sealed
+// by bundleSecrets.
+func (r *Reconciler) bundleWorkspaces(ctx context.Context, namespace string, workspaces []tektonv1.WorkspaceBinding) ([]string, error) {
+	logger := logging.FromContext(ctx)
+	bundled := []string{}
+	for _, ws := range workspaces {
+		if ws.ConfigMap == nil {
+			continue
+		}
+		cm, err := r.clients.Kube.CoreV1().ConfigMaps(namespace).Get(ctx, ws.ConfigMap.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get configmap %s bound to workspace %s: %w", ws.ConfigMap.Name, ws.Name, err)
+		}
+		cm.Kind, cm.APIVersion = "ConfigMap", corev1.SchemeGroupVersion.String()
+
+		if cm.GetAnnotations()[AnnotationBundle] != "true" {
+			logger.Debugf("%s bound to workspace %s is not annotated with %s, expecting it on the minion", cm.GetName(), ws.Name, AnnotationBundle)
+			continue
+		}
+		data, err := atypes.SerializeObjectYaml(cm)
+		if err != nil {
+			return nil, err
+		}
+		bundled = append(bundled, data)
+	}
+	return bundled, nil
+}
+
+// bundle returns the serialized resources of the namespace the PipelineRun
+// needs to run on the minion: the referenced Pipeline, the Tasks referenced
+// by the pipeline tasks and the opted in ConfigMaps bound to the workspaces.
+func (r *Reconciler) bundle(ctx context.Context, pr *tektonv1.PipelineRun) ([]string, error) {
+	bundled, err := r.bundleWorkspaces(ctx, pr.GetNamespace(), pr.Spec.Workspaces)
+	if err != nil {
+		return nil, err
+	}
+
+	spec := pr.Spec.PipelineSpec
+	if ref := pr.Spec.PipelineRef; ref != nil && ref.Name != "" && ref.Resolver == "" {
+		pipeline, err := r.clients.Tekton.TektonV1().Pipelines(pr.GetNamespace()).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pipeline %s: %w", ref.Name, err)
+		}
+		pipeline.Kind = pipelineapi.PipelineControllerName
+		pipeline.APIVersion = tektonv1.SchemeGroupVersion.String()
+		data, err := atypes.SerializeObjectYaml(pipeline)
+		if err != nil {
+			return nil, err
+		}
+		bundled = append(bundled, data)
+		spec = &pipeline.Spec
+	}
+	if spec == nil {
+		return bundled, nil
+	}
+
+	tasks, err := r.bundleTasks(ctx, pr.GetNamespace(), spec, map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+	return append(bundled, tasks...), nil
+}
+
+// bundleTaskRun returns the serialized resources of the namespace the TaskRun
+// needs to run on the minion: the referenced Task and the opted in ConfigMaps
+// bound to the workspaces.
+func (r *Reconciler) bundleTaskRun(ctx context.Context, tr *tektonv1.TaskRun) ([]string, error) {
+	bundled, err := r.bundleWorkspaces(ctx, tr.GetNamespace(), tr.Spec.Workspaces)
+	if err != nil {
+		return nil, err
+	}
+	if !isLocalTaskRef(tr.Spec.TaskRef) {
+		return bundled, nil
+	}
+	data, err := r.bundleTask(ctx, tr.GetNamespace(), tr.Spec.TaskRef.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task %s: %w", tr.Spec.TaskRef.Name, err)
+	}
+	return append(bundled, data), nil
+}
@@ -0,0 +1,165 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openshift-pipelines/tekton-armadas/pkg/apis/armada/v1alpha1"
+	atypes "github.com/openshift-pipelines/tekton-armadas/pkg/types"
+	pipelineapi "github.com/tektoncd/pipeline/pkg/apis/pipeline"
+	tektonv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/reconciler"
+	"knative.dev/pkg/system"
+)
+
+const (
+	// controlCancel and controlDelete are the controls propagated to the
+	// minion owning a run, recorded in its annotations.
+	controlCancel = "cancel"
+	controlDelete = "delete"
+)
+
+// isCancelled checks if the user asked to cancel or stop the PipelineRun.
+func isCancelled(pr *tektonv1.PipelineRun) bool {
+	return pr.IsCancelled() || pr.IsGracefullyCancelled() || pr.IsGracefullyStopped()
+}
+
+// owningMinion returns the minion the run has been dispatched to, nil if it
+// has not been dispatched or if the minion is not registered anymore.
+func (r *Reconciler) owningMinion(ctx context.Context, run metav1.Object) (*v1alpha1.Minion, error) {
+	name, ok := run.GetAnnotations()[AnnotationMinion]
+	if !ok || name == "" {
+		return nil, nil
+	}
+	minion, err := r.minionLister.Minions(system.Namespace()).Get(name)
+	if errors.IsNotFound(err) {
+		logging.FromContext(ctx).Warnf("minion %s owning run %s is not registered anymore", name, run.GetName())
+		return nil, nil
+	}
+	return minion, err
+}
+
+// controlEvent returns the type and the data of the event propagating the
+// control of the run of the kind to its minion.
+func (r *Reconciler) controlEvent(run metav1.Object, kind, specStatus, control string) (string, atypes.ArmadaControlEvent) {
+	cEvent := atypes.ArmadaControlEvent{
+		Namespace:      run.GetNamespace(),
+		Name:           run.GetName(),
+		IdempotencyKey: atypes.IdempotencyKey(r.clusterID, run.GetUID()),
+	}
+	if kind != pipelineapi.PipelineRunControllerName {
+		cEvent.Kind = kind
+	}
+	if control == controlDelete {
+		return atypes.EventTypeDelete, cEvent
+	}
+	cEvent.SpecStatus = specStatus
+	return atypes.EventTypeCancel, cEvent
+}
+
+// propagateControl sends the control of the run of the kind to its minion
+// once, and returns whether the minion acknowledged it. The control of a
+// minion in pull mode is left pending on the run until the minion pulls and
+// acknowledges it.
+func (r *Reconciler) propagateControl(ctx context.Context, minion *v1alpha1.Minion, run metav1.Object, kind, specStatus, control string) (bool, error) {
+	annotations := run.GetAnnotations()
+	if annotations[AnnotationControlPropagated] == control {
+		return true, nil
+	}
+	if minion.IsPull() {
+		if annotations[AnnotationPendingControl] == control {
+			return false, nil
+		}
+		if err := r.annotateRun(ctx, run, map[string]any{AnnotationPendingControl: control}); err != nil {
+			return false, fmt.Errorf("failed to record the pending %s of %s: %w", control, run.GetName(), err)
+		}
+		logging.FromContext(ctx).Infof("%s of %s is waiting for minion %s to pull it", control, run.GetName(), minion.GetName())
+		return false, nil
+	}
+
+	eventType, data := r.controlEvent(run, kind, specStatus, control)
+	if _, err := r.sendToMinion(ctx, minion, eventType, data); err != nil {
+		return false, fmt.Errorf("failed to propagate %s to minion %s: %w", control, minion.GetName(), err)
+	}
+	// the deleted run is gone once its finalizer is released
+	if control == controlCancel {
+		if err := r.annotateRun(ctx, run, map[string]any{AnnotationControlPropagated: control}); err != nil {
+			return true, fmt.Errorf("failed to record the %s of %s: %w", control, run.GetName(), err)
+		}
+	}
+	logging.FromContext(ctx).Infof("%s of %s has been propagated to minion %s", control, run.GetName(), minion.GetName())
+	return true, nil
+}
+
+// controlAcknowledged records the control pulled by the minion in pull mode as
+// propagated, unless another control is pending on the run since.
+func (r *Reconciler) controlAcknowledged(ctx context.Context, run metav1.Object, control string) error {
+	annotations := map[string]any{AnnotationControlPropagated: control}
+	var pending string
+	switch run.(type) {
+	case *tektonv1.PipelineRun:
+		latest, err := r.clients.Tekton.TektonV1().PipelineRuns(run.GetNamespace()).Get(ctx, run.GetName(), metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		pending = latest.GetAnnotations()[AnnotationPendingControl]
+	case *tektonv1.TaskRun:
+		latest, err := r.clients.Tekton.TektonV1().TaskRuns(run.GetNamespace()).Get(ctx, run.GetName(), metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		pending = latest.GetAnnotations()[AnnotationPendingControl]
+	}
+	if pending == control {
+		annotations[AnnotationPendingControl] = nil
+	}
+	return r.annotateRun(ctx, run, annotations)
+}
+
+// propagateCancel asks the owning minion to apply the cancellation to the
+// remote PipelineRun, the final status comes back from the minion.
+func (r *Reconciler) propagateCancel(ctx context.Context, pr *tektonv1.PipelineRun) reconciler.Event {
+	if isDone(pr.Status.GetCondition(apis.ConditionSucceeded)) {
+		return nil
+	}
+
+	minion, err := r.owningMinion(ctx, pr)
+	if err != nil {
+		return err
+	}
+	if minion == nil {
+		pr.Status.MarkFailed(tektonv1.PipelineRunReasonCancelled.String(), "PipelineRun %q was cancelled before running on a minion", pr.GetName())
+		return nil
+	}
+	_, err = r.propagateControl(ctx, minion, pr, pipelineapi.PipelineRunControllerName, string(pr.Spec.Status), controlCancel)
+	return err
+}
+
+// propagateTaskRunCancel asks the owning minion to cancel the remote TaskRun,
+// the same way as propagateCancel for the PipelineRuns.
+func (r *TaskRunReconciler) propagateTaskRunCancel(ctx context.Context, tr *tektonv1.TaskRun) reconciler.Event {
+	if isDone(tr.Status.GetCondition(apis.ConditionSucceeded)) {
+		return nil
+	}
+
+	minion, err := r.owningMinion(ctx, tr)
+	if err != nil {
+		return err
+	}
+	if minion == nil {
+		tr.Status.SetCondition(&apis.Condition{
+			Type:    apis.ConditionSucceeded,
+			Status:  corev1.ConditionFalse,
+			Reason:  tektonv1.TaskRunReasonCancelled.String(),
+			Message: fmt.Sprintf("TaskRun %q was cancelled before running on a minion", tr.GetName()),
+		})
+		return nil
+	}
+	_, err = r.propagateControl(ctx, minion, tr, pipelineapi.TaskRunControllerName, string(tr.Spec.Status), controlCancel)
+	return err
+}
@@ -0,0 +1,53 @@
+package orchestrator
+
+import (
+	"fmt"
+
+	"github.com/openshift-pipelines/tekton-armadas/pkg/apis/armada"
+	"github.com/openshift-pipelines/tekton-armadas/pkg/apis/armada/v1alpha1"
+	atypes "github.com/openshift-pipelines/tekton-armadas/pkg/types"
+	tektonv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// armadaEvent returns the event dispatching the serialized run with its
+// resources and sealed Secrets to the minion, in the newest schema version the
+// minion reported in its heartbeats. The minions not reporting one get the
+// version 1.
+func (r *Reconciler) armadaEvent(minion *v1alpha1.Minion, run metav1.Object, data string, resources []string, secrets []atypes.SealedSecret) (atypes.ArmadaEvent, error) {
+	aevent := atypes.ArmadaEvent{
+		Namespace:      run.GetNamespace(),
+		Name:           run.GetName(),
+		IdempotencyKey: atypes.IdempotencyKey(r.clusterID, run.GetUID()),
+		Secrets:        secrets,
+	}
+
+	version := atypes.NegotiateSchemaVersion(minion.Status.SchemaVersion)
+	if major, err := atypes.SchemaMajor(version); err != nil || major < 2 {
+		switch run.(type) {
+		case *tektonv1.PipelineRun:
+			aevent.PipelineRun = data
+		case *tektonv1.TaskRun:
+			aevent.TaskRun = data
+		default:
+			return atypes.ArmadaEvent{}, fmt.Errorf("cannot dispatch %T", run)
+		}
+		aevent.Resources = resources
+		return aevent, nil
+	}
+
+	aevent.SchemaVersion = version
+	aevent.ClusterID = r.clusterID
+	aevent.Minion = minion.GetName()
+	if tenant := run.GetLabels()[armada.LabelTenant]; tenant != "" {
+		aevent.Options = &atypes.DispatchOptions{Tenant: tenant}
+	}
+	for _, doc := range append([]string{data}, resources...) {
+		document, err := atypes.NewDocument(doc)
+		if err != nil {
+			return atypes.ArmadaEvent{}, fmt.Errorf("failed to create document of %s: %w", run.GetName(), err)
+		}
+		aevent.Documents = append(aevent.Documents, document)
+	}
+	return aevent, nil
+}
@@ -0,0 +1,361 @@
+package orchestrator
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	cehttp "github.com/cloudevents/sdk-go/v2/protocol/http"
+	"github.com/openshift-pipelines/tekton-armadas/pkg/apis/armada/v1alpha1"
+	"github.com/openshift-pipelines/tekton-armadas/pkg/config"
+	"github.com/openshift-pipelines/tekton-armadas/pkg/payload"
+	"github.com/openshift-pipelines/tekton-armadas/pkg/signature"
+	atypes "github.com/openshift-pipelines/tekton-armadas/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// caCertKey is the key of the CA certificate in the credentials Secret.
+	caCertKey = "ca.crt"
+	// sinkEnv is the environment variable set by a SinkBinding with the URL
+	// of the sink.
+	sinkEnv = "K_SINK"
+)
+
+var (
+	// errMinionRejected is returned when the minion answered the event with a
+	// client error, as opposed to not being reachable or busy.
+	errMinionRejected = errors.New("minion rejected the event")
+	// errPayloadTooLarge is returned when the event is over the payload
+	// size limit of the configuration.
+	errPayloadTooLarge = errors.New("event payload too large")
+	// errNoMinionURL is returned when a minion in push mode has no URL and
+	// there is no default one.
+	errNoMinionURL = errors.New("minion has no url")
+	// errPullMinion is returned when sending an event to a minion in pull
+	// mode, which pulls its events instead.
+	errPullMinion = errors.New("minion is in pull mode")
+	// errNoSink is returned when dispatching through a sink without one.
+	errNoSink = errors.New("no sink to dispatch through")
+	// errServiceAccountNotFound is returned when the minion rejected the run
+	// because its ServiceAccount does not exist there.
+	errServiceAccountNotFound = errors.New("serviceaccount not found on the minion")
+)
+
+// rejectionReason returns the reason in the body of the response of a minion
+// rejecting an event, the cloudevents client only keeps the body in the
+// arguments of the result.
+func rejectionReason(result *cehttp.Result) string {
+	for _, arg := range result.Args {
+		body, ok := arg.(string)
+		if !ok {
+			continue
+		}
+		response := struct {
+			Reason string `json:"reason"`
+		}{}
+		if err := json.Unmarshal([]byte(body), &response); err == nil && response.Reason != "" {
+			return response.Reason
+		}
+	}
+	return ""
+}
+
+// newEvent returns an event of the type with the data, with the source, the
+// compression and the payload size limit of the configuration.
+func newEvent(ctx context.Context, eventType string, data any) (cloudevents.Event, error) {
+	cfg := config.FromContextOrDefaults(ctx).Armada
+	event := cloudevents.NewEvent()
+	event.SetSource(cfg.EventSource)
+	event.SetType(eventType)
+	event.SetID(atypes.UUID())
+
+	if err := event.SetData(cloudevents.ApplicationJSON, data); err != nil {
+		return event, fmt.Errorf("failed to set data: %w", err)
+	}
+	if aevent, ok := data.(atypes.ArmadaEvent); ok {
+		if schema := atypes.DataSchema(aevent.SchemaVersion); schema != "" {
+			event.SetDataSchema(schema)
+		}
+	}
+	if err := payload.Compress(&event, cfg.Compression); err != nil {
+		return event, err
+	}
+	if size := int64(len(event.Data())); size > cfg.MaxPayloadSize {
+		return event, fmt.Errorf("%w: %d bytes over the limit of %d bytes", errPayloadTooLarge, size, cfg.MaxPayloadSize)
+	}
+	return event, nil
+}
+
+// minionURL returns the URL of the minion, the default URL of the
+// configuration when the minion has none.
+func minionURL(ctx context.Context, minion *v1alpha1.Minion) (string, error) {
+	if minion.Spec.URL != "" {
+		return minion.Spec.URL, nil
+	}
+	if url := config.FromContextOrDefaults(ctx).Armada.DefaultMinionURL; url != "" {
+		return url, nil
+	}
+	return "", fmt.Errorf("%w: %s", errNoMinionURL, minion.GetName())
+}
+
+// sendToMinion sends an event with the data to the minion, waits for the
+// minion to acknowledge it and returns the ID of the event. The events are
+// sent in chunks when they are over the chunk size of the configuration. The
+// minions in pull mode pull their events, they cannot be sent any.
+func (r *Reconciler) sendToMinion(ctx context.Context, minion *v1alpha1.Minion, eventType string, data any) (string, error) {
+	if minion.IsPull() {
+		return "", fmt.Errorf("%w: %s", errPullMinion, minion.GetName())
+	}
+	event, err := newEvent(ctx, eventType, data)
+	if err != nil {
+		return "", err
+	}
+
+	cfg := config.FromContextOrDefaults(ctx).Armada
+	chunks, err := payload.Split(event, int(cfg.ChunkSize))
+	if err != nil {
+		return "", fmt.Errorf("failed to split event in chunks: %w", err)
+	}
+
+	if cfg.DispatchThroughSink {
+		for _, chunk := range chunks {
+			if err := r.sendToSink(ctx, minion, chunk); err != nil {
+				return "", err
+			}
+		}
+		return event.ID(), nil
+	}
+
+	target, err := minionURL(ctx, minion)
+	if err != nil {
+		return "", err
+	}
+	opts, err := r.clientOptions(ctx, minion)
+	if err != nil {
+		return "", err
+	}
+	for _, chunk := range chunks {
+		if err := sendEvent(ctx, target, opts, chunk); err != nil {
+			return "", err
+		}
+	}
+	return event.ID(), nil
+}
+
+// sendToSink publishes the event for the minion to the sink of the
+// orchestrator, a Broker or a Channel with a Trigger filtering on the minion
+// extension attribute to route it to the minion. The event is signed in its
+// extension attributes as the sink does not keep the signature headers, and
+// the sink accepting the event does not mean the minion did.
+func (r *Reconciler) sendToSink(ctx context.Context, minion *v1alpha1.Minion, event cloudevents.Event) error {
+	target := config.FromContextOrDefaults(ctx).Armada.SinkURL
+	if target == "" {
+		target = os.Getenv(sinkEnv)
+	}
+	if target == "" {
+		return fmt.Errorf("%w: neither %s nor %s is set", errNoSink, config.SinkURLKey, sinkEnv)
+	}
+
+	event.SetExtension(atypes.ExtensionMinion, minion.GetName())
+	secret, err := r.minionCredentials(ctx, minion)
+	if err != nil {
+		return err
+	}
+	if secret != nil && len(secret.Data[signature.SecretKey]) > 0 {
+		signature.SignEvent(secret.Data[signature.SecretKey], &event)
+	}
+	if err := sendEvent(ctx, target, nil, event); err != nil {
+		return fmt.Errorf("failed to publish to sink: %w", err)
+	}
+	return nil
+}
+
+// isClientError checks if the status answers an event the minion is never
+// going to accept, the timeouts and the rate limiting are not.
+func isClientError(status int) bool {
+	return status >= http.StatusBadRequest && status < http.StatusInternalServerError &&
+		status != http.StatusRequestTimeout && status != http.StatusTooManyRequests
+}
+
+// sendEvent sends the event to the target and waits for it to be
+// acknowledged up to the request timeout of the configuration.
+func sendEvent(ctx context.Context, target string, opts []cehttp.Option, event cloudevents.Event) error {
+	ctx, cancel := context.WithTimeout(ctx, config.FromContextOrDefaults(ctx).Armada.RequestTimeout)
+	defer cancel()
+	ctx = cloudevents.ContextWithTarget(ctx, target)
+	ce, err := cloudevents.NewClientHTTP(opts...)
+	if err != nil {
+		return fmt.Errorf("failed to create cloudevents client: %w", err)
+	}
+
+	if result := ce.Send(ctx, event); !cloudevents.IsACK(result) {
+		var httpResult *cehttp.Result
+		if cloudevents.ResultAs(result, &httpResult) && isClientError(httpResult.StatusCode) {
+			if rejectionReason(httpResult) == atypes.ReasonServiceAccountNotFound {
+				return fmt.Errorf("%w: %w with status %d: %w", errServiceAccountNotFound, errMinionRejected, httpResult.StatusCode, result)
+			}
+			return fmt.Errorf("%w with status %d: %w", errMinionRejected, httpResult.StatusCode, result)
+		}
+		return fmt.Errorf("failed to send cloudevent: %w", result)
+	}
+	return nil
+}
+
+// clientOptions returns the options of the cloudevents client to talk to the
+// minion.
+func (r *Reconciler) clientOptions(ctx context.Context, minion *v1alpha1.Minion) ([]cehttp.Option, error) {
+	transport, err := r.minionTransport(ctx, minion)
+	if err != nil || transport == nil {
+		return nil, err
+	}
+	return []cehttp.Option{cloudevents.WithRoundTripper(transport)}, nil
+}
+
+// minionTransport is the transport to a minion built out of a version of its
+// credentials Secret.
+type minionTransport struct {
+	// version is the name and the resourceVersion of the Secret.
+	version   string
+	transport http.RoundTripper
+	// tls is the transport presenting the client certificate, nil when the
+	// default transport is used.
+	tls *http.Transport
+}
+
+// transportCache keeps the transport of each minion so its connections are
+// reused, until the credentials of the minion change.
+type transportCache struct {
+	mu         sync.Mutex
+	transports map[string]minionTransport
+}
+
+func newTransportCache() *transportCache {
+	return &transportCache{transports: map[string]minionTransport{}}
+}
+
+// get returns the transport of the minion for the version of its
+// credentials, building it when the version changed. The idle connections
+// of the transport replaced are closed.
+func (c *transportCache) get(minion, version string, build func() (minionTransport, error)) (http.RoundTripper, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached, ok := c.transports[minion]
+	if ok && cached.version == version {
+		return cached.transport, nil
+	}
+	mt, err := build()
+	if err != nil {
+		return nil, err
+	}
+	mt.version = version
+	c.forget(minion)
+	c.transports[minion] = mt
+	return mt.transport, nil
+}
+
+// forget drops the transport of the minion and closes its idle connections,
+// the caller holds the lock.
+func (c *transportCache) forget(minion string) {
+	if cached, ok := c.transports[minion]; ok && cached.tls != nil {
+		cached.tls.CloseIdleConnections()
+	}
+	delete(c.transports, minion)
+}
+
+// minionTransport returns the transport to talk to the minion, nil when the
+// minion has no credentials. When the minion has credentials, the requests
+// are signed if there is a HMAC secret and a client certificate is presented
+// if there is a TLS key pair, the ca.crt verifies the certificate of the
+// minion. The transport is reused as long as the credentials do not change.
+func (r *Reconciler) minionTransport(ctx context.Context, minion *v1alpha1.Minion) (http.RoundTripper, error) {
+	secret, err := r.minionCredentials(ctx, minion)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil {
+		r.transports.mu.Lock()
+		defer r.transports.mu.Unlock()
+		r.transports.forget(minion.GetName())
+		return nil, nil
+	}
+
+	return r.transports.get(minion.GetName(), secret.GetName()+"/"+secret.GetResourceVersion(), func() (minionTransport, error) {
+		return newMinionTransport(minion, secret)
+	})
+}
+
+// newMinionTransport builds the transport to the minion out of its
+// credentials Secret.
+func newMinionTransport(minion *v1alpha1.Minion, secret *corev1.Secret) (minionTransport, error) {
+	mt := minionTransport{transport: http.DefaultTransport}
+	tlsConfig, err := minionTLSConfig(secret)
+	if err != nil {
+		return minionTransport{}, fmt.Errorf("invalid tls credentials for minion %s: %w", minion.GetName(), err)
+	}
+	if tlsConfig != nil {
+		base, ok := http.DefaultTransport.(*http.Transport)
+		if !ok {
+			return minionTransport{}, fmt.Errorf("unexpected default transport %T", http.DefaultTransport)
+		}
+		mt.tls = base.Clone()
+		mt.tls.TLSClientConfig = tlsConfig
+		mt.transport = mt.tls
+	}
+
+	key, hasKey := secret.Data[signature.SecretKey]
+	switch {
+	case hasKey && len(key) > 0:
+		mt.transport = &signature.Transport{Secret: key, Base: mt.transport}
+	case tlsConfig == nil:
+		return minionTransport{}, fmt.Errorf("credentials secret %s of minion %s has neither a %s key nor a tls key pair", secret.GetName(), minion.GetName(), signature.SecretKey)
+	}
+	return mt, nil
+}
+
+// minionCredentials returns the credentials Secret of the minion, nil when
+// the minion has none.
+func (r *Reconciler) minionCredentials(ctx context.Context, minion *v1alpha1.Minion) (*corev1.Secret, error) {
+	if minion.Spec.CredentialsRef == nil {
+		return nil, nil
+	}
+	secret, err := r.clients.Kube.CoreV1().Secrets(minion.GetNamespace()).Get(ctx, minion.Spec.CredentialsRef.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credentials of minion %s: %w", minion.GetName(), err)
+	}
+	return secret, nil
+}
+
+// minionTLSConfig returns the TLS configuration out of the credentials
+// Secret, nil when the Secret has no TLS key pair.
+func minionTLSConfig(secret *corev1.Secret) (*tls.Config, error) {
+	certPEM, hasCert := secret.Data[corev1.TLSCertKey]
+	keyPEM, hasKey := secret.Data[corev1.TLSPrivateKeyKey]
+	if !hasCert && !hasKey {
+		return nil, nil
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+	if caPEM, ok := secret.Data[caCertKey]; ok {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no valid certificate in %s", caCertKey)
+		}
+		config.RootCAs = pool
+	}
+	return config, nil
+}
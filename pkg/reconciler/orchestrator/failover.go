@@ -0,0 +1,155 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/openshift-pipelines/tekton-armadas/pkg/config"
+	tektonv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/reconciler"
+	"knative.dev/pkg/system"
+)
+
+const (
+	// FailoverPolicyNone leaves the run waiting for its minion to come back.
+	FailoverPolicyNone = "none"
+	// FailoverPolicyRedispatch dispatches the run to another minion.
+	FailoverPolicyRedispatch = "redispatch"
+	// FailoverPolicyFail marks the run as failed.
+	FailoverPolicyFail = "fail"
+)
+
+// failoverPolicy returns the failover policy of the run, none when it is not
+// set or not valid.
+func failoverPolicy(run metav1.Object) string {
+	switch policy := run.GetAnnotations()[AnnotationFailoverPolicy]; policy {
+	case FailoverPolicyRedispatch, FailoverPolicyFail:
+		return policy
+	default:
+		return FailoverPolicyNone
+	}
+}
+
+// lostMinion returns the name of the minion owning the run when it has been
+// deleted or unready for longer than the grace period, otherwise how long to
+// wait before checking again if the minion is unready.
+func (r *Reconciler) lostMinion(ctx context.Context, run metav1.Object, now time.Time) (string, time.Duration, error) {
+	name := run.GetAnnotations()[AnnotationMinion]
+	if name == "" {
+		return "", 0, nil
+	}
+	minion, err := r.minionLister.Minions(system.Namespace()).Get(name)
+	if errors.IsNotFound(err) {
+		return name, 0, nil
+	} else if err != nil {
+		return "", 0, err
+	}
+	cond := minion.Status.GetCondition(apis.ConditionReady)
+	if cond == nil || !cond.IsFalse() {
+		return "", 0, nil
+	}
+	if wait := cond.LastTransitionTime.Inner.Add(config.FromContextOrDefaults(ctx).Dispatch.FailoverGracePeriod).Sub(now); wait > 0 {
+		return "", wait, nil
+	}
+	return name, 0, nil
+}
+
+// redispatchPatch forgets the minion the run has been dispatched to and the
+// past attempts, so the run is dispatched again from scratch.
+func redispatchPatch() ([]byte, error) {
+	return annotationsPatch(map[string]any{
+		AnnotationMinion:            nil,
+		AnnotationRemoteName:        nil,
+		AnnotationTaskResults:       nil,
+		AnnotationDispatchedAt:      nil,
+		AnnotationEventID:           nil,
+		AnnotationDispatchAttempts:  nil,
+		AnnotationDispatchLastError: nil,
+		AnnotationDispatchNextRetry: nil,
+	})
+}
+
+// checkPipelineRunMinion applies the failover policy of the PipelineRun when
+// the minion it has been dispatched to is lost.
+func (r *Reconciler) checkPipelineRunMinion(ctx context.Context, pr *tektonv1.PipelineRun) reconciler.Event {
+	policy := failoverPolicy(pr)
+	if policy == FailoverPolicyNone || isDone(pr.Status.GetCondition(apis.ConditionSucceeded)) {
+		return nil
+	}
+	minion, wait, err := r.lostMinion(ctx, pr, time.Now())
+	switch {
+	case err != nil:
+		return err
+	case wait > 0:
+		return controller.NewRequeueAfter(wait)
+	case minion == "":
+		return nil
+	}
+
+	logging.FromContext(ctx).Warnf("minion %s owning pipelinerun %s has been lost, applying failover policy %s", minion, pr.GetName(), policy)
+	if policy == FailoverPolicyFail {
+		pr.Status.MarkFailed(ReasonMinionLost, "Minion %s running the PipelineRun has been lost", minion)
+		return reconciler.NewEvent(corev1.EventTypeWarning, ReasonMinionLost, "Minion %s running the PipelineRun has been lost", minion)
+	}
+
+	patch, err := redispatchPatch()
+	if err != nil {
+		return err
+	}
+	if _, err := r.clients.Tekton.TektonV1().PipelineRuns(pr.GetNamespace()).Patch(ctx, pr.GetName(), types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to reset the dispatch of pipelinerun: %w", err)
+	}
+	pr.Status.MarkRunning(ReasonRedispatching, "Minion %s running the PipelineRun has been lost, dispatching to another minion", minion)
+	controller.GetEventRecorder(ctx).Eventf(pr, corev1.EventTypeWarning, ReasonMinionLost, "Minion %s running the PipelineRun has been lost, dispatching to another minion", minion)
+	// the status is updated once ReconcileKind returns, the run is
+	// dispatched on the next reconcile
+	return controller.NewRequeueImmediately()
+}
+
+// checkTaskRunMinion applies the failover policy of the TaskRun when the
+// minion it has been dispatched to is lost.
+func (r *TaskRunReconciler) checkTaskRunMinion(ctx context.Context, tr *tektonv1.TaskRun) reconciler.Event {
+	policy := failoverPolicy(tr)
+	if policy == FailoverPolicyNone || isDone(tr.Status.GetCondition(apis.ConditionSucceeded)) {
+		return nil
+	}
+	minion, wait, err := r.lostMinion(ctx, tr, time.Now())
+	switch {
+	case err != nil:
+		return err
+	case wait > 0:
+		return controller.NewRequeueAfter(wait)
+	case minion == "":
+		return nil
+	}
+
+	logging.FromContext(ctx).Warnf("minion %s owning taskrun %s has been lost, applying failover policy %s", minion, tr.GetName(), policy)
+	if policy == FailoverPolicyFail {
+		tr.Status.SetCondition(&apis.Condition{
+			Type:    apis.ConditionSucceeded,
+			Status:  corev1.ConditionFalse,
+			Reason:  ReasonMinionLost,
+			Message: fmt.Sprintf("Minion %s running the TaskRun has been lost", minion),
+		})
+		return reconciler.NewEvent(corev1.EventTypeWarning, ReasonMinionLost, "Minion %s running the TaskRun has been lost", minion)
+	}
+
+	patch, err := redispatchPatch()
+	if err != nil {
+		return err
+	}
+	if _, err := r.clients.Tekton.TektonV1().TaskRuns(tr.GetNamespace()).Patch(ctx, tr.GetName(), types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to reset the dispatch of taskrun: %w", err)
+	}
+	markTaskRunWaiting(tr, ReasonRedispatching, fmt.Sprintf("Minion %s running the TaskRun has been lost, dispatching to another minion", minion))
+	controller.GetEventRecorder(ctx).Eventf(tr, corev1.EventTypeWarning, ReasonMinionLost, "Minion %s running the TaskRun has been lost, dispatching to another minion", minion)
+	return controller.NewRequeueImmediately()
+}
@@ -0,0 +1,108 @@
+package orchestrator
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/openshift-pipelines/tekton-armadas/pkg/apis/armada/v1alpha1"
+	"github.com/openshift-pipelines/tekton-armadas/pkg/config"
+	atypes "github.com/openshift-pipelines/tekton-armadas/pkg/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/reconciler"
+	"knative.dev/pkg/system"
+)
+
+// heartbeatCheckInterval is how often the minions missing their heartbeats
+// are looked for.
+const heartbeatCheckInterval = 15 * time.Second
+
+// handleHeartbeat records the health reported by the minion on its status
+// and marks it as ready.
+func (r *Reconciler) handleHeartbeat(ctx context.Context, response http.ResponseWriter, request *http.Request, event cloudevents.Event, body []byte) {
+	hb := atypes.ArmadaHeartbeatEvent{}
+	if err := event.DataAs(&hb); err != nil {
+		writeResponse(ctx, response, http.StatusBadRequest, "invalid heartbeat event data")
+		return
+	}
+	minion, err := r.signedMinion(ctx, hb.Minion, request, event.Type(), body)
+	if err != nil {
+		writePullError(ctx, response, err)
+		return
+	}
+
+	if err := r.updateMinionStatus(ctx, minion, func(status *v1alpha1.MinionStatus) {
+		status.Version = hb.Version
+		status.TektonVersion = hb.TektonVersion
+		status.RunningPipelineRuns = hb.RunningPipelineRuns
+		status.PendingPipelineRuns = hb.PendingPipelineRuns
+		status.Allocatable = hb.Allocatable
+		status.SchemaVersion = hb.SchemaVersion
+		status.LastHeartbeatTime = &metav1.Time{Time: time.Now()}
+		status.MarkReady()
+	}); err != nil {
+		logging.FromContext(ctx).Errorf("failed to record heartbeat of minion %s: %v", minion.GetName(), err)
+		writeResponse(ctx, response, http.StatusInternalServerError, "failed to record heartbeat")
+		return
+	}
+	writeResponse(ctx, response, http.StatusAccepted, "accepted")
+}
+
+// updateMinionStatus applies the change to the latest status of the minion.
+func (r *Reconciler) updateMinionStatus(ctx context.Context, minion *v1alpha1.Minion, change func(*v1alpha1.MinionStatus)) error {
+	minions := r.clients.Armada.ArmadaV1alpha1().Minions(minion.GetNamespace())
+	return reconciler.RetryUpdateConflicts(func(int) error {
+		latest, err := minions.Get(ctx, minion.GetName(), metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		change(&latest.Status)
+		_, err = minions.UpdateStatus(ctx, latest, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// checkHeartbeats marks as not ready the minions which have not sent a
+// heartbeat for the heartbeat timeout of the configuration. The minions which
+// never sent one are left alone as they may not be reporting their health.
+func (r *Reconciler) checkHeartbeats(ctx context.Context) {
+	logger := logging.FromContext(ctx)
+	timeout := config.FromContextOrDefaults(r.configStore.ToContext(ctx)).Armada.HeartbeatTimeout
+	minions, err := r.minionLister.Minions(system.Namespace()).List(labels.Everything())
+	if err != nil {
+		logger.Errorf("failed to list minions: %v", err)
+		return
+	}
+	now := time.Now()
+	for _, minion := range minions {
+		last := minion.Status.LastHeartbeatTime
+		if last == nil || now.Sub(last.Time) < timeout || minion.Status.IsNotReady() {
+			continue
+		}
+		if err := r.updateMinionStatus(ctx, minion, func(status *v1alpha1.MinionStatus) {
+			status.MarkNotReady(v1alpha1.MinionReasonHeartbeatMissed, "No heartbeat since %s", last.UTC().Format(time.RFC3339))
+		}); err != nil {
+			logger.Errorf("failed to mark minion %s as not ready: %v", minion.GetName(), err)
+			continue
+		}
+		logger.Warnf("minion %s missed its heartbeats since %s, it will not get runs until it reports again", minion.GetName(), last.UTC().Format(time.RFC3339))
+	}
+}
+
+// startHeartbeatCheck looks for the minions missing their heartbeats until
+// the context is done.
+func (r *Reconciler) startHeartbeatCheck(ctx context.Context) {
+	ticker := time.NewTicker(heartbeatCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.checkHeartbeats(ctx)
+		}
+	}
+}
@@ -1,5 +1,78 @@
 package orchestrator
 
-import "github.com/openshift-pipelines/tekton-armadas/pkg/apis/armada"
+import (
+	"github.com/openshift-pipelines/tekton-armadas/pkg/apis/armada"
+	atypes "github.com/openshift-pipelines/tekton-armadas/pkg/types"
+)
 
-var LabelOrchestration = armada.GroupName + "/orchestration"
+var (
+	LabelOrchestration = armada.GroupName + "/orchestration"
+	// AnnotationMinionSelector is a label selector evaluated against the
+	// labels of the minions to choose where the PipelineRun is dispatched.
+	AnnotationMinionSelector = armada.GroupName + "/minion-selector"
+	// AnnotationMinion is the name of the minion the PipelineRun has been dispatched to.
+	AnnotationMinion = armada.GroupName + "/minion"
+	// AnnotationRemoteName is the name of the run created by the minion.
+	AnnotationRemoteName = armada.GroupName + "/remote-name"
+	// AnnotationTaskResults holds the results of the tasks of the run on the
+	// minion as JSON, by pipeline task and result name.
+	AnnotationTaskResults = armada.GroupName + "/task-results"
+	// AnnotationDispatchedAt is the RFC3339 time the run has been dispatched at.
+	AnnotationDispatchedAt = armada.GroupName + "/dispatched-at"
+	// AnnotationEventID is the ID of the event that dispatched the run.
+	AnnotationEventID = armada.GroupName + "/event-id"
+	// AnnotationBundle opts in a ConfigMap or a Secret bound to a workspace,
+	// or an image pull secret of the service account of the run, to be sent
+	// to the minion along with the run. The Secrets are encrypted with the
+	// public key of the minion.
+	AnnotationBundle = armada.GroupName + "/bundle"
+	// AnnotationDispatchAttempts is the number of failed attempts to dispatch the run.
+	AnnotationDispatchAttempts = armada.GroupName + "/dispatch-attempts"
+	// AnnotationDispatchLastError is the error of the last failed attempt to dispatch the run.
+	AnnotationDispatchLastError = armada.GroupName + "/dispatch-last-error"
+	// AnnotationDispatchNextRetry is the RFC3339 time of the next attempt to dispatch the run.
+	AnnotationDispatchNextRetry = armada.GroupName + "/dispatch-next-retry"
+	// AnnotationFailoverPolicy is what to do with the run when the minion it
+	// has been dispatched to is lost: none, redispatch or fail.
+	AnnotationFailoverPolicy = armada.GroupName + "/failover-policy"
+	// AnnotationPending set to "true" asks for the TaskRun to be dispatched
+	// to a minion. Tekton has no pending TaskRuns and rejects any other
+	// spec.status than a cancellation, the orchestrator cluster is not
+	// expected to run them itself.
+	AnnotationPending = armada.GroupName + "/pending"
+	// AnnotationPendingControl is the control, cancel or delete, waiting to
+	// be pulled and acknowledged by the minion in pull mode owning the run.
+	AnnotationPendingControl = armada.GroupName + "/pending-control"
+	// AnnotationControlPropagated is the last control, cancel or delete,
+	// acknowledged by the minion owning the run.
+	AnnotationControlPropagated = armada.GroupName + "/control-propagated"
+)
+
+const (
+	// ReasonDispatched is set on the PipelineRun once it has been sent to a minion.
+	ReasonDispatched = "Dispatched"
+	// ReasonAssigned is set on the run once it has been assigned to a minion
+	// in pull mode, until the minion acknowledges it.
+	ReasonAssigned = "Assigned"
+	// ReasonNoMatchingMinion is set on the PipelineRun when no minion matches its selector.
+	ReasonNoMatchingMinion = "NoMatchingMinion"
+	// ReasonInvalidMinionSelector is set on the PipelineRun when its minion selector cannot be parsed.
+	ReasonInvalidMinionSelector = "InvalidMinionSelector"
+	// ReasonDispatchRetrying is set on the run when the minion did not
+	// acknowledge it and the dispatch will be retried.
+	ReasonDispatchRetrying = "DispatchRetrying"
+	// ReasonDispatchFailed is set on the run when it could not be dispatched
+	// after the maximum number of attempts.
+	ReasonDispatchFailed = "DispatchFailed"
+	// ReasonRejected is recorded when the minion refused the run.
+	ReasonRejected = "Rejected"
+	// ReasonMinionLost is set on the run failed because the minion it has
+	// been dispatched to is lost, and recorded when the run is failed over.
+	ReasonMinionLost = "MinionLost"
+	// ReasonServiceAccountNotFound is set on the run failed because its
+	// ServiceAccount does not exist on the minion.
+	ReasonServiceAccountNotFound = atypes.ReasonServiceAccountNotFound
+	// ReasonRedispatching is set on the run waiting to be dispatched again
+	// after the minion it has been dispatched to is lost.
+	ReasonRedispatching = "Redispatching"
+)
@@ -0,0 +1,184 @@
+package orchestrator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/openshift-pipelines/tekton-armadas/pkg/apis/armada/v1alpha1"
+	atypes "github.com/openshift-pipelines/tekton-armadas/pkg/types"
+	pipelineapi "github.com/tektoncd/pipeline/pkg/apis/pipeline"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/logging"
+)
+
+var errUnauthorized = errors.New("unauthorized")
+
+// flushWriter flushes the response after each write so the logs are
+// streamed as they come.
+type flushWriter struct {
+	w http.ResponseWriter
+}
+
+func (fw flushWriter) Write(p []byte) (int, error) {
+	n, err := fw.w.Write(p)
+	if err == nil {
+		err = http.NewResponseController(fw.w).Flush()
+	}
+	return n, err
+}
+
+// authorizeLogs checks the bearer token of the request belongs to a user
+// allowed to get the PipelineRuns of the namespace.
+func (r *Reconciler) authorizeLogs(ctx context.Context, request *http.Request, namespace string) error {
+	token, ok := strings.CutPrefix(request.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return fmt.Errorf("%w: missing bearer token", errUnauthorized)
+	}
+
+	tr, err := r.clients.Kube.AuthenticationV1().TokenReviews().Create(ctx, &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to review token: %w", err)
+	}
+	if !tr.Status.Authenticated {
+		return fmt.Errorf("%w: invalid token", errUnauthorized)
+	}
+
+	extra := map[string]authorizationv1.ExtraValue{}
+	for k, v := range tr.Status.User.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+	sar, err := r.clients.Kube.AuthorizationV1().SubjectAccessReviews().Create(ctx, &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   tr.Status.User.Username,
+			UID:    tr.Status.User.UID,
+			Groups: tr.Status.User.Groups,
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      "get",
+				Group:     pipelineapi.GroupName,
+				Resource:  "pipelineruns",
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to review access: %w", err)
+	}
+	if !sar.Status.Allowed {
+		return fmt.Errorf("%w: %s cannot get pipelineruns in namespace %s", errUnauthorized, tr.Status.User.Username, namespace)
+	}
+	return nil
+}
+
+// handleLogs streams the logs of an orchestrated PipelineRun by proxying the
+// request to the minion it has been dispatched to. The logs are followed
+// until the PipelineRun is done with ?follow=true.
+func (r *Reconciler) handleLogs(ctx context.Context) http.HandlerFunc {
+	logger := logging.FromContext(ctx)
+	return func(response http.ResponseWriter, request *http.Request) {
+		ctx := r.configStore.ToContext(ctx)
+		namespace, name := request.PathValue("namespace"), request.PathValue("name")
+		if err := r.authorizeLogs(request.Context(), request, namespace); err != nil {
+			logger.Errorf("rejecting logs request of %s/%s: %v", namespace, name, err)
+			if errors.Is(err, errUnauthorized) {
+				writeResponse(ctx, response, http.StatusForbidden, err.Error())
+				return
+			}
+			writeResponse(ctx, response, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		pr, err := r.pipelineRunLister.PipelineRuns(namespace).Get(name)
+		if apierrors.IsNotFound(err) {
+			writeResponse(ctx, response, http.StatusNotFound, err.Error())
+			return
+		} else if err != nil {
+			writeResponse(ctx, response, http.StatusInternalServerError, err.Error())
+			return
+		}
+		minion, err := r.owningMinion(request.Context(), pr)
+		if err != nil {
+			writeResponse(ctx, response, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if minion == nil {
+			writeResponse(ctx, response, http.StatusNotFound, fmt.Sprintf("pipelinerun %s/%s has not been dispatched to a minion", namespace, name))
+			return
+		}
+		if minion.IsPull() {
+			writeResponse(ctx, response, http.StatusNotImplemented, fmt.Sprintf("cannot stream logs from minion %s in pull mode", minion.GetName()))
+			return
+		}
+
+		remote, err := r.requestLogs(request.Context(), minion, atypes.ArmadaLogsRequest{
+			Namespace:      namespace,
+			Name:           name,
+			IdempotencyKey: atypes.IdempotencyKey(r.clusterID, pr.GetUID()),
+			Follow:         request.URL.Query().Get("follow") == "true",
+		})
+		if err != nil {
+			logger.Errorf("failed to get logs of %s/%s from minion %s: %v", namespace, name, minion.GetName(), err)
+			writeResponse(ctx, response, http.StatusBadGateway, err.Error())
+			return
+		}
+		defer remote.Close()
+
+		response.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		response.WriteHeader(http.StatusOK)
+		if _, err := io.Copy(flushWriter{w: response}, remote); err != nil {
+			logger.Debugf("stopped streaming logs of %s/%s: %v", namespace, name, err)
+		}
+	}
+}
+
+// requestLogs asks the minion for the logs of the PipelineRun and returns the
+// stream.
+func (r *Reconciler) requestLogs(ctx context.Context, minion *v1alpha1.Minion, lr atypes.ArmadaLogsRequest) (io.ReadCloser, error) {
+	body, err := json.Marshal(lr)
+	if err != nil {
+		return nil, err
+	}
+	base, err := minionURL(ctx, minion)
+	if err != nil {
+		return nil, err
+	}
+	target, err := url.JoinPath(base, "logs")
+	if err != nil {
+		return nil, fmt.Errorf("invalid url of minion %s: %w", minion.GetName(), err)
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	// the type is part of the signature of the request
+	request.Header.Set("Ce-Type", atypes.EventTypeLogs)
+
+	transport, err := r.minionTransport(ctx, minion)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Transport: transport}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		defer response.Body.Close()
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return nil, fmt.Errorf("minion answered %s: %s", response.Status, strings.TrimSpace(string(message)))
+	}
+	return response.Body, nil
+}
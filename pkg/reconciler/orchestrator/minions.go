@@ -0,0 +1,246 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/openshift-pipelines/tekton-armadas/pkg/apis/armada/v1alpha1"
+	"github.com/openshift-pipelines/tekton-armadas/pkg/scheduler"
+	tektonv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+	"knative.dev/pkg/configmap"
+	"knative.dev/pkg/kmeta"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/system"
+)
+
+var (
+	errNoMatchingMinion      = errors.New("no minion matching")
+	errInvalidMinionSelector = errors.New("invalid minion selector")
+)
+
+// minionSelector returns the selector from the run annotation, matching
+// every minion when the annotation is not set.
+func minionSelector(run metav1.Object) (labels.Selector, error) {
+	value, ok := run.GetAnnotations()[AnnotationMinionSelector]
+	if !ok || value == "" {
+		return labels.Everything(), nil
+	}
+	selector, err := labels.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %s", errInvalidMinionSelector, value, err.Error())
+	}
+	return selector, nil
+}
+
+const (
+	// noMatchingMinionRequeue is how often a run waiting for a minion is
+	// reconciled again, the runs done on the minions free their capacity
+	// without changing the minions.
+	noMatchingMinionRequeue = 30 * time.Second
+	// recentWindow is how long a run assigned or dispatched to a minion is
+	// counted in its load without being seen in the informer cache yet.
+	recentWindow = time.Minute
+)
+
+// recentDispatches are the runs assigned or dispatched to each minion lately,
+// the informer cache may not have caught up with their minion annotation.
+type recentDispatches struct {
+	mu   sync.Mutex
+	runs map[string]map[string]time.Time
+}
+
+func newRecentDispatches() *recentDispatches {
+	return &recentDispatches{runs: map[string]map[string]time.Time{}}
+}
+
+// record counts the run in the load of the minion for recentWindow.
+func (d *recentDispatches) record(minion, run string, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.runs[minion] == nil {
+		d.runs[minion] = map[string]time.Time{}
+	}
+	d.runs[minion][run] = now
+}
+
+// list returns the runs recently dispatched to the minion, forgetting the
+// ones older than recentWindow.
+func (d *recentDispatches) list(minion string, now time.Time) []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	runs := []string{}
+	for run, at := range d.runs[minion] {
+		if now.Sub(at) > recentWindow {
+			delete(d.runs[minion], run)
+			continue
+		}
+		runs = append(runs, run)
+	}
+	if len(d.runs[minion]) == 0 {
+		delete(d.runs, minion)
+	}
+	return runs
+}
+
+// runKey identifies the PipelineRun or the TaskRun in the loads.
+func runKey(run metav1.Object) string {
+	kind := "pipelinerun"
+	if _, ok := run.(*tektonv1.TaskRun); ok {
+		kind = "taskrun"
+	}
+	return kind + "/" + run.GetNamespace() + "/" + run.GetName()
+}
+
+// minionLoad returns the number of runs in flight on the minion: the
+// PipelineRuns and the TaskRuns annotated with it which are not done, along
+// with the ones recently dispatched to it. It is never below the running
+// PipelineRuns the minion reports, which may come from elsewhere.
+func (r *Reconciler) minionLoad(minion *v1alpha1.Minion, now time.Time) (int, error) {
+	inFlight := map[string]bool{}
+	for _, indexer := range []cache.Indexer{r.pipelineRunIndexer, r.taskRunIndexer} {
+		objs, err := indexer.ByIndex(minionIndex, minion.GetName())
+		if err != nil {
+			return 0, fmt.Errorf("failed to list the runs of minion %s: %w", minion.GetName(), err)
+		}
+		for _, obj := range objs {
+			switch run := obj.(type) {
+			case *tektonv1.PipelineRun:
+				if !run.IsDone() {
+					inFlight[runKey(run)] = true
+				}
+			case *tektonv1.TaskRun:
+				if !run.IsDone() {
+					inFlight[runKey(run)] = true
+				}
+			}
+		}
+	}
+	for _, run := range r.recent.list(minion.GetName(), now) {
+		inFlight[run] = true
+	}
+	return max(len(inFlight), int(minion.Status.RunningPipelineRuns)), nil
+}
+
+// hasCapacity checks if the minion can accept another run with the runs it
+// has in flight.
+func hasCapacity(minion *v1alpha1.Minion, inFlight int) bool {
+	return minion.Spec.Capacity == 0 || inFlight < int(minion.Spec.Capacity)
+}
+
+// minionEventHandler calls resync when a minion is added or deleted and when
+// its spec, its labels or its readiness change, but not on the status update
+// of each of its heartbeats.
+func minionEventHandler(resync func()) cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(interface{}) { resync() },
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			if minionChanged(oldObj, newObj) {
+				resync()
+			}
+		},
+		DeleteFunc: func(interface{}) { resync() },
+	}
+}
+
+// minionChanged checks if the update of the minion may change where the runs
+// are dispatched.
+func minionChanged(oldObj, newObj interface{}) bool {
+	old, ok := oldObj.(*v1alpha1.Minion)
+	if !ok {
+		return true
+	}
+	minion, ok := newObj.(*v1alpha1.Minion)
+	if !ok {
+		return true
+	}
+	return !equality.Semantic.DeepEqual(old.Spec, minion.Spec) ||
+		!equality.Semantic.DeepEqual(old.GetLabels(), minion.GetLabels()) ||
+		old.Status.IsNotReady() != minion.Status.IsNotReady()
+}
+
+// getMinion returns the minion the run should be dispatched to, as chosen by
+// the scheduler out of the minions registered in the orchestrator namespace
+// matching the run selector, not marked as unready and having some capacity
+// left.
+func (r *Reconciler) getMinion(ctx context.Context, run kmeta.Accessor) (*v1alpha1.Minion, error) {
+	selector, err := minionSelector(run)
+	if err != nil {
+		return nil, err
+	}
+	minions, err := r.minionLister.Minions(system.Namespace()).List(selector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list minions: %w", err)
+	}
+
+	now := time.Now()
+	eligible := make([]*v1alpha1.Minion, 0, len(minions))
+	loads := scheduler.Loads{}
+	for _, minion := range minions {
+		if minion.Status.IsNotReady() {
+			continue
+		}
+		inFlight, err := r.minionLoad(minion, now)
+		if err != nil {
+			return nil, err
+		}
+		if hasCapacity(minion, inFlight) {
+			eligible = append(eligible, minion)
+			loads[minion.GetName()] = inFlight
+		}
+	}
+	if len(eligible) == 0 {
+		return nil, fmt.Errorf("%w %q ready and with capacity left in namespace %s", errNoMatchingMinion, selector.String(), system.Namespace())
+	}
+
+	sched := r.getScheduler()
+	minion, err := sched.Schedule(run, eligible, loads)
+	if err != nil {
+		return nil, fmt.Errorf("scheduler %s failed: %w", sched.Name(), err)
+	}
+	logging.FromContext(ctx).Debugf("scheduler %s chose minion %s out of %d eligible minions", sched.Name(), minion.GetName(), len(eligible))
+	return minion, nil
+}
+
+func (r *Reconciler) getScheduler() scheduler.Scheduler {
+	r.configMu.RLock()
+	defer r.configMu.RUnlock()
+	return r.scheduler
+}
+
+// watchSchedulerConfig switches the scheduler strategy whenever the scheduler
+// ConfigMap changes, keeping the current one when the strategy is not valid.
+func (r *Reconciler) watchSchedulerConfig(ctx context.Context, cmw configmap.Watcher) {
+	logger := logging.FromContext(ctx)
+	observer := func(cm *corev1.ConfigMap) {
+		sched, err := scheduler.NewFromConfigMap(cm)
+		if err != nil {
+			logger.Errorf("failed to update scheduler from configmap %s: %v", cm.GetName(), err)
+			return
+		}
+
+		r.configMu.Lock()
+		defer r.configMu.Unlock()
+		if r.scheduler.Name() == sched.Name() {
+			return
+		}
+		logger.Infof("switching scheduler from %s to %s", r.scheduler.Name(), sched.Name())
+		r.scheduler = sched
+	}
+
+	if dw, ok := cmw.(configmap.DefaultingWatcher); ok {
+		dw.WatchWithDefault(corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: scheduler.ConfigName, Namespace: system.Namespace()},
+			Data:       map[string]string{scheduler.StrategyKey: scheduler.DefaultStrategy},
+		}, observer)
+		return
+	}
+	cmw.Watch(scheduler.ConfigName, observer)
+}
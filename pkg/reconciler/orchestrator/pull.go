@@ -0,0 +1,483 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/openshift-pipelines/tekton-armadas/pkg/apis/armada/v1alpha1"
+	"github.com/openshift-pipelines/tekton-armadas/pkg/signature"
+	atypes "github.com/openshift-pipelines/tekton-armadas/pkg/types"
+	pipelineapi "github.com/tektoncd/pipeline/pkg/apis/pipeline"
+	tektonv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/reconciler"
+	"knative.dev/pkg/system"
+)
+
+const (
+	// pollTimeout is how long a poll of a minion waits for events, below
+	// the timeout of the server.
+	pollTimeout = 30 * time.Second
+	// pollInterval is how often the runs assigned to a polling minion are
+	// looked up.
+	pollInterval = time.Second
+	// ackTimeout is how long a delivered run waits to be acknowledged before
+	// being delivered again.
+	ackTimeout = 2 * time.Minute
+)
+
+var (
+	errNotPullMinion = errors.New("minion is not in pull mode")
+	errUnknownMinion = errors.New("unknown minion")
+	// errNoMinionSecret is returned for a minion without a HMAC secret, its
+	// requests cannot be authenticated and are rejected.
+	errNoMinionSecret = errors.New("minion has no HMAC secret to authenticate its requests")
+)
+
+// minionIndex is the index of the runs by the minion they are assigned or
+// dispatched to.
+const minionIndex = "minion"
+
+// minionIndexFunc indexes the runs on their minion annotation.
+func minionIndexFunc(obj interface{}) ([]string, error) {
+	run, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, err
+	}
+	if minion := run.GetAnnotations()[AnnotationMinion]; minion != "" {
+		return []string{minion}, nil
+	}
+	return nil, nil
+}
+
+// deliveredEvent is a dispatch event delivered to a minion in pull mode and
+// waiting to be acknowledged.
+type deliveredEvent struct {
+	minion   string
+	run      string
+	deadline time.Time
+	ack      func(context.Context) error
+	// reject records the rejection of the event by the minion.
+	reject func(context.Context, atypes.ArmadaAckFailure) error
+}
+
+// outbox tracks the events delivered to the minions in pull mode until they
+// acknowledge them. The events are not kept there, they are built from the
+// runs assigned to the minion and from the controls pending on them so they
+// survive a restart of the orchestrator. The deliveries are only known to the
+// replica which made them, the orchestrator runs a single replica.
+type outbox struct {
+	mu sync.Mutex
+	// delivered are the events waiting to be acknowledged by ID.
+	delivered map[string]deliveredEvent
+}
+
+func newOutbox() *outbox {
+	return &outbox{
+		delivered: map[string]deliveredEvent{},
+	}
+}
+
+// isDelivered checks if the run has been delivered and is still waiting to
+// be acknowledged, forgetting the deliveries not acknowledged in time.
+func (o *outbox) isDelivered(run string, now time.Time) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	found := false
+	for id, de := range o.delivered {
+		if now.After(de.deadline) {
+			delete(o.delivered, id)
+			continue
+		}
+		if de.run == run {
+			found = true
+		}
+	}
+	return found
+}
+
+func (o *outbox) deliver(id string, de deliveredEvent) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.delivered[id] = de
+}
+
+// acknowledge returns and forgets the event delivered to the minion.
+func (o *outbox) acknowledge(minion, id string) (deliveredEvent, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	de, ok := o.delivered[id]
+	if !ok || de.minion != minion {
+		return deliveredEvent{}, false
+	}
+	delete(o.delivered, id)
+	return de, true
+}
+
+// isAssigned checks if the pending run has been assigned to a minion in pull
+// mode which did not acknowledge it yet.
+func isAssigned(pending bool, cond *apis.Condition) bool {
+	return pending && cond != nil && cond.Reason == ReasonAssigned
+}
+
+// assign records on the run the minion in pull mode it has been assigned to.
+func (r *Reconciler) assign(ctx context.Context, run metav1.Object, minion *v1alpha1.Minion) error {
+	if err := r.annotateRun(ctx, run, map[string]any{AnnotationMinion: minion.GetName()}); err != nil {
+		return err
+	}
+	r.recent.record(minion.GetName(), runKey(run), time.Now())
+	return nil
+}
+
+// annotateRun patches the annotations of the PipelineRun or the TaskRun, a
+// nil value removes the annotation.
+func (r *Reconciler) annotateRun(ctx context.Context, run metav1.Object, annotations map[string]any) error {
+	patch, err := annotationsPatch(annotations)
+	if err != nil {
+		return err
+	}
+	switch run.(type) {
+	case *tektonv1.PipelineRun:
+		_, err = r.clients.Tekton.TektonV1().PipelineRuns(run.GetNamespace()).Patch(ctx, run.GetName(), types.MergePatchType, patch, metav1.PatchOptions{})
+	case *tektonv1.TaskRun:
+		_, err = r.clients.Tekton.TektonV1().TaskRuns(run.GetNamespace()).Patch(ctx, run.GetName(), types.MergePatchType, patch, metav1.PatchOptions{})
+	default:
+		err = fmt.Errorf("unsupported run %T", run)
+	}
+	return err
+}
+
+// rejectionCondition returns the Succeeded condition of a run the minion
+// rejected, the rejection is not going to change on another attempt.
+func rejectionCondition(minion *v1alpha1.Minion, kind string, failure atypes.ArmadaAckFailure) *apis.Condition {
+	reason := failure.Reason
+	if reason == "" {
+		reason = ReasonRejected
+	}
+	return &apis.Condition{
+		Type:    apis.ConditionSucceeded,
+		Status:  corev1.ConditionFalse,
+		Reason:  reason,
+		Message: fmt.Sprintf("Minion %s rejected the %s: %s", minion.GetName(), kind, failure.Message),
+	}
+}
+
+// rejectedPatch forgets the minion which never created the rejected run and
+// records why it has been rejected.
+func rejectedPatch(failure atypes.ArmadaAckFailure) map[string]any {
+	return map[string]any{
+		AnnotationMinion:            nil,
+		AnnotationDispatchLastError: failure.Message,
+	}
+}
+
+// pipelineRunRejected marks the PipelineRun assigned to the minion as failed
+// as the minion rejected it.
+func (r *Reconciler) pipelineRunRejected(ctx context.Context, pr *tektonv1.PipelineRun, minion *v1alpha1.Minion, failure atypes.ArmadaAckFailure) error {
+	if err := reconciler.RetryUpdateConflicts(func(int) error {
+		latest, err := r.clients.Tekton.TektonV1().PipelineRuns(pr.GetNamespace()).Get(ctx, pr.GetName(), metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if !isAssigned(latest.Spec.Status == tektonv1.PipelineRunSpecStatusPending, latest.Status.GetCondition(apis.ConditionSucceeded)) {
+			return nil
+		}
+		latest.Status.SetCondition(rejectionCondition(minion, pipelineapi.PipelineRunControllerName, failure))
+		_, err = r.clients.Tekton.TektonV1().PipelineRuns(latest.GetNamespace()).UpdateStatus(ctx, latest, metav1.UpdateOptions{})
+		return err
+	}); err != nil {
+		return fmt.Errorf("failed to mark pipelinerun as rejected: %w", err)
+	}
+	return r.annotateRun(ctx, pr, rejectedPatch(failure))
+}
+
+// taskRunRejected marks the TaskRun assigned to the minion as failed as the
+// minion rejected it.
+func (r *Reconciler) taskRunRejected(ctx context.Context, tr *tektonv1.TaskRun, minion *v1alpha1.Minion, failure atypes.ArmadaAckFailure) error {
+	if err := reconciler.RetryUpdateConflicts(func(int) error {
+		latest, err := r.clients.Tekton.TektonV1().TaskRuns(tr.GetNamespace()).Get(ctx, tr.GetName(), metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if !isAssigned(isTaskRunPending(latest), latest.Status.GetCondition(apis.ConditionSucceeded)) {
+			return nil
+		}
+		latest.Status.SetCondition(rejectionCondition(minion, pipelineapi.TaskRunControllerName, failure))
+		_, err = r.clients.Tekton.TektonV1().TaskRuns(latest.GetNamespace()).UpdateStatus(ctx, latest, metav1.UpdateOptions{})
+		return err
+	}); err != nil {
+		return fmt.Errorf("failed to mark taskrun as rejected: %w", err)
+	}
+	return r.annotateRun(ctx, tr, rejectedPatch(failure))
+}
+
+// controlRejected records the control the minion rejected as propagated,
+// delivering it again would not change the answer of the minion.
+func (r *Reconciler) controlRejected(ctx context.Context, run metav1.Object, control string, failure atypes.ArmadaAckFailure) error {
+	logging.FromContext(ctx).Warnf("%s of %s has been rejected by its minion: %s", control, run.GetName(), failure.Message)
+	return r.controlAcknowledged(ctx, run, control)
+}
+
+// collect returns the events waiting for the minion which are not waiting to
+// be acknowledged: the controls pending on the runs dispatched to it and the
+// runs assigned to it.
+func (r *Reconciler) collect(ctx context.Context, minion *v1alpha1.Minion) []cloudevents.Event {
+	logger := logging.FromContext(ctx)
+	now := time.Now()
+	events := []cloudevents.Event{}
+
+	add := func(run string, eventType string, data any, ack func(ctx context.Context, eventID string) error, reject func(context.Context, atypes.ArmadaAckFailure) error) {
+		event, err := newEvent(ctx, eventType, data)
+		if err != nil {
+			logger.Errorf("failed to create the %s event of %s: %v", eventType, run, err)
+			return
+		}
+		r.outbox.deliver(event.ID(), deliveredEvent{
+			minion:   minion.GetName(),
+			run:      run,
+			deadline: now.Add(ackTimeout),
+			ack:      func(ctx context.Context) error { return ack(ctx, event.ID()) },
+			reject:   reject,
+		})
+		events = append(events, event)
+	}
+
+	prs, err := r.pipelineRunIndexer.ByIndex(minionIndex, minion.GetName())
+	if err != nil {
+		logger.Errorf("failed to list the pipelineruns of minion %s: %v", minion.GetName(), err)
+	}
+	for _, obj := range prs {
+		pr, ok := obj.(*tektonv1.PipelineRun)
+		if !ok {
+			continue
+		}
+		run := "pipelinerun/" + pr.GetNamespace() + "/" + pr.GetName()
+		if control := pr.GetAnnotations()[AnnotationPendingControl]; control != "" {
+			if !r.outbox.isDelivered(control+"/"+run, now) {
+				eventType, data := r.controlEvent(pr, pipelineapi.PipelineRunControllerName, string(pr.Spec.Status), control)
+				add(control+"/"+run, eventType, data, func(ctx context.Context, _ string) error {
+					return r.controlAcknowledged(ctx, pr, control)
+				}, func(ctx context.Context, failure atypes.ArmadaAckFailure) error {
+					return r.controlRejected(ctx, pr, control, failure)
+				})
+			}
+			continue
+		}
+		if !isAssigned(pr.Spec.Status == tektonv1.PipelineRunSpecStatusPending, pr.Status.GetCondition(apis.ConditionSucceeded)) ||
+			r.outbox.isDelivered(run, now) {
+			continue
+		}
+		aevent, err := r.pipelineRunEvent(ctx, pr, minion)
+		if err != nil {
+			logger.Errorf("failed to create the dispatch event of %s: %v", run, err)
+			continue
+		}
+		add(run, atypes.EventTypeDispatch, aevent, func(ctx context.Context, eventID string) error {
+			return r.markDispatched(ctx, pr, minion, eventID)
+		}, func(ctx context.Context, failure atypes.ArmadaAckFailure) error {
+			return r.pipelineRunRejected(ctx, pr, minion, failure)
+		})
+	}
+
+	trs, err := r.taskRunIndexer.ByIndex(minionIndex, minion.GetName())
+	if err != nil {
+		logger.Errorf("failed to list the taskruns of minion %s: %v", minion.GetName(), err)
+	}
+	for _, obj := range trs {
+		tr, ok := obj.(*tektonv1.TaskRun)
+		if !ok {
+			continue
+		}
+		run := "taskrun/" + tr.GetNamespace() + "/" + tr.GetName()
+		if control := tr.GetAnnotations()[AnnotationPendingControl]; control != "" {
+			if !r.outbox.isDelivered(control+"/"+run, now) {
+				eventType, data := r.controlEvent(tr, pipelineapi.TaskRunControllerName, string(tr.Spec.Status), control)
+				add(control+"/"+run, eventType, data, func(ctx context.Context, _ string) error {
+					return r.controlAcknowledged(ctx, tr, control)
+				}, func(ctx context.Context, failure atypes.ArmadaAckFailure) error {
+					return r.controlRejected(ctx, tr, control, failure)
+				})
+			}
+			continue
+		}
+		if !isAssigned(isTaskRunPending(tr), tr.Status.GetCondition(apis.ConditionSucceeded)) ||
+			r.outbox.isDelivered(run, now) {
+			continue
+		}
+		aevent, err := r.taskRunEvent(ctx, tr, minion)
+		if err != nil {
+			logger.Errorf("failed to create the dispatch event of %s: %v", run, err)
+			continue
+		}
+		add(run, atypes.EventTypeDispatch, aevent, func(ctx context.Context, eventID string) error {
+			return r.markTaskRunDispatched(ctx, tr, minion, eventID)
+		}, func(ctx context.Context, failure atypes.ArmadaAckFailure) error {
+			return r.taskRunRejected(ctx, tr, minion, failure)
+		})
+	}
+	return events
+}
+
+// pullingMinion returns the minion in pull mode the request comes from, after
+// checking its signature.
+func (r *Reconciler) pullingMinion(ctx context.Context, name string, request *http.Request, eventType string, body []byte) (*v1alpha1.Minion, error) {
+	minion, err := r.signedMinion(ctx, name, request, eventType, body)
+	if err != nil {
+		return nil, err
+	}
+	if !minion.IsPull() {
+		return nil, fmt.Errorf("%w: %s", errNotPullMinion, name)
+	}
+	return minion, nil
+}
+
+// signedMinion returns the minion the request comes from, after checking its
+// signature. The requests of a minion without a HMAC secret are rejected, a
+// client certificate only authenticates the orchestrator to the minion.
+func (r *Reconciler) signedMinion(ctx context.Context, name string, request *http.Request, eventType string, body []byte) (*v1alpha1.Minion, error) {
+	minion, err := r.minionLister.Minions(system.Namespace()).Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %w", errUnknownMinion, name, err)
+	}
+	key, err := r.minionSecretKey(ctx, minion)
+	if err != nil {
+		return nil, err
+	}
+	if err := signature.Verify(key, request.Header.Get(signature.HeaderTimestamp), eventType, signature.RequestExtensions(request), request.Header.Get(signature.HeaderSignature), body, time.Now(), signature.DefaultMaxSkew); err != nil {
+		return nil, err
+	}
+	return minion, nil
+}
+
+// minionSecretKey returns the HMAC secret shared with the minion,
+// errNoMinionSecret when it has none.
+func (r *Reconciler) minionSecretKey(ctx context.Context, minion *v1alpha1.Minion) ([]byte, error) {
+	if minion.Spec.CredentialsRef == nil {
+		return nil, fmt.Errorf("%w: %s has no credentials", errNoMinionSecret, minion.GetName())
+	}
+	secret, err := r.clients.Kube.CoreV1().Secrets(minion.GetNamespace()).Get(ctx, minion.Spec.CredentialsRef.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credentials of minion %s: %w", minion.GetName(), err)
+	}
+	key, ok := secret.Data[signature.SecretKey]
+	if !ok || len(key) == 0 {
+		return nil, fmt.Errorf("%w: %s has no %s in its credentials", errNoMinionSecret, minion.GetName(), signature.SecretKey)
+	}
+	return key, nil
+}
+
+// writePullError answers the error of a request of a minion.
+func writePullError(ctx context.Context, response http.ResponseWriter, err error) {
+	logging.FromContext(ctx).Errorf("rejecting minion request: %v", err)
+	switch {
+	case errors.Is(err, errUnknownMinion), errors.Is(err, errNotPullMinion), errors.Is(err, errNoMinionSecret):
+		writeResponse(ctx, response, http.StatusForbidden, err.Error())
+	case errors.Is(err, signature.ErrMissingSignature), errors.Is(err, signature.ErrInvalidSignature), errors.Is(err, signature.ErrStaleRequest):
+		writeResponse(ctx, response, http.StatusUnauthorized, err.Error())
+	default:
+		writeResponse(ctx, response, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// handlePull waits for events for the minion up to pollTimeout and answers
+// them as a JSON array of cloudevents, empty when there are none. Each event
+// is signed with the secret of the minion, which verifies them as it does
+// for the events delivered by a broker.
+func (r *Reconciler) handlePull(ctx context.Context, response http.ResponseWriter, request *http.Request, event cloudevents.Event, body []byte) {
+	pe := atypes.ArmadaPullEvent{}
+	if err := event.DataAs(&pe); err != nil {
+		writeResponse(ctx, response, http.StatusBadRequest, "invalid pull event data")
+		return
+	}
+	minion, err := r.pullingMinion(ctx, pe.Minion, request, event.Type(), body)
+	if err != nil {
+		writePullError(ctx, response, err)
+		return
+	}
+	key, err := r.minionSecretKey(ctx, minion)
+	if err != nil {
+		writePullError(ctx, response, err)
+		return
+	}
+
+	timeout := time.NewTimer(pollTimeout)
+	defer timeout.Stop()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	events := r.collect(ctx, minion)
+poll:
+	for len(events) == 0 {
+		select {
+		case <-request.Context().Done():
+			return
+		case <-timeout.C:
+			events = []cloudevents.Event{}
+			break poll
+		case <-ticker.C:
+			events = r.collect(ctx, minion)
+		}
+	}
+
+	for i := range events {
+		signature.SignEvent(key, &events[i])
+	}
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(response).Encode(events); err != nil {
+		logging.FromContext(ctx).Errorf("failed to write back events to minion %s: %v", minion.GetName(), err)
+	}
+}
+
+// handleAck marks the runs acknowledged by the minion as dispatched and their
+// controls as propagated, and the runs it rejected as failed.
+func (r *Reconciler) handleAck(ctx context.Context, response http.ResponseWriter, request *http.Request, event cloudevents.Event, body []byte) {
+	logger := logging.FromContext(ctx)
+	ae := atypes.ArmadaAckEvent{}
+	if err := event.DataAs(&ae); err != nil {
+		writeResponse(ctx, response, http.StatusBadRequest, "invalid ack event data")
+		return
+	}
+	minion, err := r.pullingMinion(ctx, ae.Minion, request, event.Type(), body)
+	if err != nil {
+		writePullError(ctx, response, err)
+		return
+	}
+
+	for _, id := range ae.IDs {
+		de, ok := r.outbox.acknowledge(minion.GetName(), id)
+		if !ok {
+			logger.Warnf("minion %s acknowledged event %s which is not waiting for it, it expired or was delivered by another replica", minion.GetName(), id)
+			continue
+		}
+		if err := de.ack(ctx); err != nil {
+			logger.Errorf("failed to record the acknowledgement of %s: %v", de.run, err)
+			continue
+		}
+		logger.Infof("%s has been pulled by minion %s with event %s", de.run, minion.GetName(), id)
+	}
+	for _, failure := range ae.Failures {
+		de, ok := r.outbox.acknowledge(minion.GetName(), failure.ID)
+		if !ok {
+			logger.Warnf("minion %s rejected event %s which is not waiting for it: %s", minion.GetName(), failure.ID, failure.Message)
+			continue
+		}
+		if err := de.reject(ctx, failure); err != nil {
+			logger.Errorf("failed to record the rejection of %s: %v", de.run, err)
+			continue
+		}
+		logger.Warnf("%s has been rejected by minion %s: %s", de.run, minion.GetName(), failure.Message)
+	}
+	writeResponse(ctx, response, http.StatusAccepted, "acknowledged")
+}
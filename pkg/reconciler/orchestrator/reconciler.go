@@ -18,18 +18,31 @@ package orchestrator
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	atypes "github.com/openshift-pipelines/tekton-armadas/pkg/types"
 	"k8s.io/apimachinery/pkg/types"
 
-	cloudevents "github.com/cloudevents/sdk-go/v2"
 	"github.com/openshift-pipelines/tekton-armadas/pkg/apis/armada"
+	"github.com/openshift-pipelines/tekton-armadas/pkg/apis/armada/v1alpha1"
+	minionInformerv1alpha1 "github.com/openshift-pipelines/tekton-armadas/pkg/client/injection/informers/armada/v1alpha1/minion"
+	armadaListersv1alpha1 "github.com/openshift-pipelines/tekton-armadas/pkg/client/listers/armada/v1alpha1"
 	"github.com/openshift-pipelines/tekton-armadas/pkg/clients"
+	"github.com/openshift-pipelines/tekton-armadas/pkg/config"
+	"github.com/openshift-pipelines/tekton-armadas/pkg/scheduler"
 	pipelineapi "github.com/tektoncd/pipeline/pkg/apis/pipeline"
 	tektonv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
 	tektonPipelineRunInformerv1 "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1/pipelinerun"
+	tektonTaskRunInformerv1 "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1/taskrun"
 	tektonPipelineRunReconcilerv1 "github.com/tektoncd/pipeline/pkg/client/injection/reconciler/pipeline/v1/pipelinerun"
+	tektonListersv1 "github.com/tektoncd/pipeline/pkg/client/listers/pipeline/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"knative.dev/pkg/apis"
 	"knative.dev/pkg/configmap"
 	"knative.dev/pkg/controller"
 	"knative.dev/pkg/kmeta"
@@ -38,94 +51,240 @@ import (
 )
 
 type Reconciler struct {
-	clients *clients.Clients
+	clients      *clients.Clients
+	minionLister armadaListersv1alpha1.MinionLister
+	// clusterID identifies the orchestrator cluster in the idempotency keys.
+	clusterID string
+
+	// outbox and the run listers serve the minions in pull mode, the
+	// indexers find the runs of a minion.
+	outbox             *outbox
+	pipelineRunLister  tektonListersv1.PipelineRunLister
+	taskRunLister      tektonListersv1.TaskRunLister
+	pipelineRunIndexer cache.Indexer
+	taskRunIndexer     cache.Indexer
+	// recent are the runs dispatched lately, counted in the load of their
+	// minion until the informer cache catches up.
+	recent *recentDispatches
+
+	// transports are the transports to the minions in push mode.
+	transports *transportCache
+
+	// configStore holds the configuration of the orchestrator, attached to
+	// the context of the reconciles and of the requests of the minions.
+	configStore *config.Store
+
+	// configMu guards the scheduler updated from its ConfigMap.
+	configMu  sync.RWMutex
+	scheduler scheduler.Scheduler
 }
 
 // enqueue only the pipelineruns which are in `started` state
 // pipelinerun will have a label `pipelinesascode.tekton.dev/state` to describe the state.
 func checkStateAndEnqueue(impl *controller.Impl) func(obj interface{}) {
 	return func(obj interface{}) {
-		pr, err := kmeta.DeletionHandlingAccessor(obj)
-		if err == nil {
-			val, AnnotationExist := pr.GetAnnotations()[LabelOrchestration]
-			if AnnotationExist && val == "true" {
-				impl.EnqueueKey(types.NamespacedName{Namespace: pr.GetNamespace(), Name: pr.GetName()})
-			}
+		if !isOrchestrated(obj) {
+			return
+		}
+		if pr, err := kmeta.DeletionHandlingAccessor(obj); err == nil {
+			impl.EnqueueKey(types.NamespacedName{Namespace: pr.GetNamespace(), Name: pr.GetName()})
 		}
 	}
 }
 
-func ctrlOpts() func(impl *controller.Impl) controller.Options {
+// isOrchestrated checks if the object has been annotated to be orchestrated.
+func isOrchestrated(obj interface{}) bool {
+	pr, err := kmeta.DeletionHandlingAccessor(obj)
+	if err != nil {
+		return false
+	}
+	val, exist := pr.GetAnnotations()[LabelOrchestration]
+	return exist && val == "true"
+}
+
+func ctrlOpts(configStore *config.Store) func(impl *controller.Impl) controller.Options {
 	return func(_ *controller.Impl) controller.Options {
 		return controller.Options{
-			FinalizerName: armada.GroupName,
-			PromoteFilterFunc: func(obj interface{}) bool {
-				val, exist := obj.(*tektonv1.PipelineRun).GetAnnotations()[LabelOrchestration]
-				return exist && val == "true"
-			},
+			FinalizerName:     armada.GroupName,
+			PromoteFilterFunc: isOrchestrated,
+			ConfigStore:       configStore,
 		}
 	}
 }
 
-// NewReconciler creates a Reconciler and returns the result of NewImpl.
-func NewReconciler(ctx context.Context, _ configmap.Watcher) *controller.Impl {
-	pipelineRunInformer := tektonPipelineRunInformerv1.Get(ctx)
+// shared is the Reconciler of both the PipelineRuns and the TaskRuns, they
+// share the outbox of the minions in pull mode, the configuration and the
+// scheduler.
+var shared struct {
+	once       sync.Once
+	reconciler *Reconciler
+}
+
+// sharedReconciler returns the Reconciler created by the first controller
+// asking for it, along with its server and heartbeat check.
+func sharedReconciler(ctx context.Context, cmw configmap.Watcher) *Reconciler {
+	shared.once.Do(func() {
+		r := newReconciler(ctx, cmw)
+		pipelineRunInformer := tektonPipelineRunInformerv1.Get(ctx)
+		taskRunInformer := tektonTaskRunInformerv1.Get(ctx)
+		for _, informer := range []cache.SharedIndexInformer{pipelineRunInformer.Informer(), taskRunInformer.Informer()} {
+			if err := informer.AddIndexers(cache.Indexers{minionIndex: minionIndexFunc}); err != nil {
+				logging.FromContext(ctx).Panicf("Couldn't index the runs by minion: %+v", err)
+			}
+		}
+		r.pipelineRunLister = pipelineRunInformer.Lister()
+		r.taskRunLister = taskRunInformer.Lister()
+		r.pipelineRunIndexer = pipelineRunInformer.Informer().GetIndexer()
+		r.taskRunIndexer = taskRunInformer.Informer().GetIndexer()
+		go r.startServer(ctx)
+		go r.startLogsServer(ctx)
+		go r.startHeartbeatCheck(ctx)
+		shared.reconciler = r
+	})
+	return shared.reconciler
+}
 
+// newReconciler creates the Reconciler with what is needed to dispatch runs
+// to the minions.
+func newReconciler(ctx context.Context, cmw configmap.Watcher) *Reconciler {
 	newClients, err := clients.NewClients()
 	if err != nil {
 		logging.FromContext(ctx).Panicf("Couldn't register clients: %+v", err)
 	}
 
+	clusterID, err := getClusterID(ctx, newClients)
+	if err != nil {
+		logging.FromContext(ctx).Panicf("Couldn't get the cluster ID: %+v", err)
+	}
+
 	r := &Reconciler{
-		clients: newClients,
+		clients:      newClients,
+		minionLister: minionInformerv1alpha1.Get(ctx).Lister(),
+		clusterID:    clusterID,
+		outbox:       newOutbox(),
+		recent:       newRecentDispatches(),
+		transports:   newTransportCache(),
+		configStore:  config.NewStore(logging.FromContext(ctx).Named("config-store")),
+		scheduler:    scheduler.NewRoundRobin(),
+	}
+	r.configStore.WatchConfigs(cmw)
+	r.watchSchedulerConfig(ctx, cmw)
+	return r
+}
+
+// getClusterID returns the UID of the kube-system namespace, as there is no
+// other identifier of the cluster in the Kubernetes API.
+func getClusterID(ctx context.Context, c *clients.Clients) (string, error) {
+	ns, err := c.Kube.CoreV1().Namespaces().Get(ctx, metav1.NamespaceSystem, metav1.GetOptions{})
+	if err != nil {
+		return "", err
 	}
-	impl := tektonPipelineRunReconcilerv1.NewImpl(ctx, r, ctrlOpts())
+	return string(ns.GetUID()), nil
+}
+
+// NewReconciler creates a Reconciler and returns the result of NewImpl.
+func NewReconciler(ctx context.Context, cmw configmap.Watcher) *controller.Impl {
+	pipelineRunInformer := tektonPipelineRunInformerv1.Get(ctx)
+	minionInformer := minionInformerv1alpha1.Get(ctx)
+
+	r := sharedReconciler(ctx, cmw)
+	impl := tektonPipelineRunReconcilerv1.NewImpl(ctx, r, ctrlOpts(r.configStore))
 
 	if _, err := pipelineRunInformer.Informer().AddEventHandler(controller.HandleAll(checkStateAndEnqueue(impl))); err != nil {
 		logging.FromContext(ctx).Panicf("Couldn't register PipelineRun informer event handler: %+v", err)
 	}
 
+	// when the minions change, give another chance to the pipelineruns waiting to be dispatched
+	if _, err := minionInformer.Informer().AddEventHandler(minionEventHandler(func() {
+		impl.FilteredGlobalResync(isOrchestrated, pipelineRunInformer.Informer())
+	})); err != nil {
+		logging.FromContext(ctx).Panicf("Couldn't register Minion informer event handler: %+v", err)
+	}
+
 	return impl
 }
 
-func (r *Reconciler) HandlePendingPipelineRun(ctx context.Context, pr *tektonv1.PipelineRun) reconciler.Event {
-	logger := logging.FromContext(ctx)
+// pipelineRunEvent returns the event dispatching the PipelineRun with the
+// resources it needs to the minion.
+func (r *Reconciler) pipelineRunEvent(ctx context.Context, pr *tektonv1.PipelineRun, minion *v1alpha1.Minion) (atypes.ArmadaEvent, error) {
+	pr = pr.DeepCopy()
 	pr.Kind = pipelineapi.PipelineRunControllerName
 	pr.APIVersion = tektonv1.SchemeGroupVersion.String()
 	data, err := atypes.SerializeObjectYaml(pr)
 	if err != nil {
-		return err
+		return atypes.ArmadaEvent{}, err
 	}
-	logger.Infof("Sending PipelineRun %s to minion: %s", pr.GetName(), data)
 
-	aevent := atypes.ArmadaEvent{
-		PipelineRun: data,
-		Namespace:   pr.GetNamespace(),
+	resources, err := r.bundle(ctx, pr)
+	if err != nil {
+		return atypes.ArmadaEvent{}, fmt.Errorf("failed to bundle resources of pipelinerun: %w", err)
+	}
+	secrets, err := r.bundleSecrets(ctx, pr.GetNamespace(), pr.Spec.TaskRunTemplate.ServiceAccountName, pr.Spec.Workspaces)
+	if err != nil {
+		return atypes.ArmadaEvent{}, fmt.Errorf("failed to bundle secrets of pipelinerun: %w", err)
+	}
+	sealed, err := r.sealSecrets(ctx, minion, secrets)
+	if err != nil {
+		return atypes.ArmadaEvent{}, err
 	}
 
-	event := cloudevents.NewEvent()
+	return r.armadaEvent(minion, pr, data, resources, sealed)
+}
 
-	// TODO: we need to do this right
-	event.SetSource("https://github.com/openshift-pipelines/tekton-armadas")
-	event.SetType("armada.tekton.dev/v1")
-	event.SetID(atypes.UUID())
+func (r *Reconciler) HandlePendingPipelineRun(ctx context.Context, pr *tektonv1.PipelineRun) reconciler.Event {
+	logger := logging.FromContext(ctx)
+	minion, err := r.getMinion(ctx, pr)
+	switch {
+	case errors.Is(err, errNoMatchingMinion):
+		pr.Status.MarkRunning(ReasonNoMatchingMinion, "Waiting for a minion to be available: %s", err.Error())
+		controller.GetEventRecorder(ctx).Event(pr, corev1.EventTypeWarning, ReasonNoMatchingMinion, err.Error())
+		return controller.NewRequeueAfter(noMatchingMinionRequeue)
+	case errors.Is(err, errInvalidMinionSelector):
+		pr.Status.MarkRunning(ReasonInvalidMinionSelector, "Cannot dispatch PipelineRun: %s", err.Error())
+		return reconciler.NewEvent(corev1.EventTypeWarning, ReasonInvalidMinionSelector, err.Error())
+	case err != nil:
+		return err
+	}
+	if minion.IsPull() {
+		if err := r.assign(ctx, pr, minion); err != nil {
+			return fmt.Errorf("failed to assign pipelinerun: %w", err)
+		}
+		pr.Status.MarkRunning(ReasonAssigned, "Waiting for minion %s to pull the PipelineRun", minion.GetName())
+		return reconciler.NewEvent(corev1.EventTypeNormal, ReasonAssigned, "PipelineRun has been assigned to minion %s", minion.GetName())
+	}
+	logger.Infof("PipelineRun %s will be dispatched to minion %s", pr.GetName(), minion.GetName())
 
-	if err := event.SetData(cloudevents.ApplicationJSON, aevent); err != nil {
-		return fmt.Errorf("failed to set data: %w", err)
+	aevent, err := r.pipelineRunEvent(ctx, pr, minion)
+	switch {
+	case errors.Is(err, errNoPublicKey):
+		return r.pipelineRunDispatchFailed(ctx, pr, minion, err)
+	case err != nil:
+		return err
 	}
 
-	// TODO: we need to do this righ
-	ctx = cloudevents.ContextWithTarget(ctx, "http://localhost:8081")
-	ce, err := cloudevents.NewClientHTTP()
+	eventID, err := r.sendToMinion(ctx, minion, atypes.EventTypeDispatch, aevent)
 	if err != nil {
-		return fmt.Errorf("failed to create cloudevents client: %w", err)
+		return r.pipelineRunDispatchFailed(ctx, pr, minion, err)
 	}
-
-	if result := ce.Send(ctx, event); !cloudevents.IsACK(result) {
-		return fmt.Errorf("failed to send cloudevent: %w", result)
+	if err := r.markDispatched(ctx, pr, minion, eventID); err != nil {
+		return fmt.Errorf("failed to mark pipelinerun as dispatched: %w", err)
 	}
+	logger.Infof("PipelineRun %s has been dispatched to minion %s with event %s", pr.GetName(), minion.GetName(), eventID)
 
-	return nil
+	return reconciler.NewEvent(corev1.EventTypeNormal, ReasonDispatched, "PipelineRun has been dispatched to minion %s", minion.GetName())
+}
+
+// isWaitingForDispatch checks if the PipelineRun is pending and has not been
+// sent to a minion yet.
+func isWaitingForDispatch(pr *tektonv1.PipelineRun) bool {
+	if pr.Spec.Status != tektonv1.PipelineRunSpecStatusPending {
+		return false
+	}
+	cond := pr.Status.GetCondition(apis.ConditionSucceeded)
+	if cond == nil {
+		return true
+	}
+	return isPreDispatchReason(cond.Reason)
 }
 
 // ReconcileKind implements Interface.ReconcileKind.
@@ -133,13 +292,38 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, pr *tektonv1.PipelineRun
 	// This logger has all the context necessary to identify which resource is being reconciled.
 	logger := logging.FromContext(ctx)
 
-	if pr.Spec.Status == tektonv1.PipelineRunSpecStatusPending && pr.Status.GetConditions() == nil {
+	if isWaitingForDispatch(pr) {
 		label, labelExist := pr.GetLabels()[pipelineapi.PipelineLabelKey]
 		if !labelExist || label == "" {
 			return nil
 		}
+		if wait := dispatchRetryIn(pr, time.Now()); wait > 0 {
+			return controller.NewRequeueAfter(wait)
+		}
 		logger.Infof("Reconciling PipelineRun %s, status: %s", pr.GetName(), pr.Spec.Status)
 		return r.HandlePendingPipelineRun(ctx, pr)
 	}
+	if isCancelled(pr) {
+		return r.propagateCancel(ctx, pr)
+	}
+	return r.checkPipelineRunMinion(ctx, pr)
+}
+
+// FinalizeKind implements Interface.FinalizeKind, the finalizer is only
+// released once the minion acknowledged the deletion of the remote
+// PipelineRun. The acknowledgement of a minion in pull mode is recorded on the
+// PipelineRun, which is reconciled again.
+func (r *Reconciler) FinalizeKind(ctx context.Context, pr *tektonv1.PipelineRun) reconciler.Event {
+	minion, err := r.owningMinion(ctx, pr)
+	if err != nil || minion == nil {
+		return err
+	}
+	acknowledged, err := r.propagateControl(ctx, minion, pr, pipelineapi.PipelineRunControllerName, "", controlDelete)
+	if err != nil {
+		return err
+	}
+	if !acknowledged {
+		return controller.NewRequeueAfter(ackTimeout)
+	}
 	return nil
 }
@@ -0,0 +1,180 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/openshift-pipelines/tekton-armadas/pkg/apis/armada/v1alpha1"
+	"github.com/openshift-pipelines/tekton-armadas/pkg/config"
+	tektonv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/reconciler"
+)
+
+var errDispatchExhausted = errors.New("giving up dispatching")
+
+// isPreDispatchReason checks if the reason of the Succeeded condition is one
+// set by the orchestrator before the run is dispatched.
+func isPreDispatchReason(reason string) bool {
+	return reason == ReasonNoMatchingMinion || reason == ReasonInvalidMinionSelector || reason == ReasonDispatchRetrying || reason == ReasonRedispatching
+}
+
+// dispatchAttempts returns the number of failed attempts to dispatch the run.
+func dispatchAttempts(run metav1.Object) int {
+	attempts, err := strconv.Atoi(run.GetAnnotations()[AnnotationDispatchAttempts])
+	if err != nil {
+		return 0
+	}
+	return attempts
+}
+
+// dispatchRetryIn returns how long to wait before the next attempt to
+// dispatch the run, zero or negative when it can be dispatched right away.
+func dispatchRetryIn(run metav1.Object, now time.Time) time.Duration {
+	value, ok := run.GetAnnotations()[AnnotationDispatchNextRetry]
+	if !ok {
+		return 0
+	}
+	next, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return 0
+	}
+	return next.Sub(now)
+}
+
+// failureReason returns the reason of the event recorded for a failed
+// attempt, telling apart the minion rejecting the run from the minion not
+// being reachable.
+func failureReason(sendErr error) string {
+	if errors.Is(sendErr, errMinionRejected) {
+		return ReasonRejected
+	}
+	return ReasonDispatchFailed
+}
+
+// failedReason returns the reason of the run failed because it could not be
+// dispatched.
+func failedReason(sendErr error) string {
+	switch {
+	case errors.Is(sendErr, errServiceAccountNotFound):
+		return ReasonServiceAccountNotFound
+	case errors.Is(sendErr, errMinionRejected):
+		return ReasonRejected
+	}
+	return ReasonDispatchFailed
+}
+
+// isRetriable checks if another attempt may dispatch the run: a payload too
+// large is not going to get any smaller and the minion answering with a
+// client error, such as a policy violation or an event not matching the
+// schema, is going to answer the same way.
+func isRetriable(sendErr error) bool {
+	return !errors.Is(sendErr, errPayloadTooLarge) && !errors.Is(sendErr, errMinionRejected)
+}
+
+// annotationsPatch returns a merge patch of the annotations, the nil values
+// remove the annotation.
+func annotationsPatch(annotations map[string]any) ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"metadata": map[string]any{"annotations": annotations},
+	})
+}
+
+// dispatchedPatch records the minion the run has been dispatched to with the
+// event and clears the state of the failed attempts.
+func dispatchedPatch(minion *v1alpha1.Minion, eventID string) ([]byte, error) {
+	return annotationsPatch(map[string]any{
+		AnnotationMinion:            minion.GetName(),
+		AnnotationDispatchedAt:      time.Now().UTC().Format(time.RFC3339),
+		AnnotationEventID:           eventID,
+		AnnotationDispatchAttempts:  nil,
+		AnnotationDispatchLastError: nil,
+		AnnotationDispatchNextRetry: nil,
+	})
+}
+
+// recordDispatchFailure records the failed attempt on the annotations of the
+// run with the patch function and returns the attempt number and the delay
+// before the next one, errDispatchExhausted when there are no attempts left.
+func (r *Reconciler) recordDispatchFailure(ctx context.Context, run metav1.Object, sendErr error, patch func(context.Context, []byte) error) (int, time.Duration, error) {
+	b := config.FromContextOrDefaults(ctx).Dispatch.Backoff
+	attempt := dispatchAttempts(run) + 1
+	annotations := map[string]any{
+		AnnotationDispatchAttempts:  strconv.Itoa(attempt),
+		AnnotationDispatchLastError: sendErr.Error(),
+		AnnotationDispatchNextRetry: nil,
+	}
+	var delay time.Duration
+	if !b.Exhausted(attempt) && isRetriable(sendErr) {
+		delay = b.Delay(attempt)
+		annotations[AnnotationDispatchNextRetry] = time.Now().Add(delay).UTC().Format(time.RFC3339)
+	}
+
+	data, err := annotationsPatch(annotations)
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := patch(ctx, data); err != nil {
+		return 0, 0, fmt.Errorf("failed to record dispatch attempt: %w", err)
+	}
+	if delay == 0 {
+		return attempt, 0, fmt.Errorf("%w after %d attempts: %s", errDispatchExhausted, attempt, sendErr.Error())
+	}
+	return attempt, delay, nil
+}
+
+// pipelineRunDispatchFailed retries the dispatch of the PipelineRun later or
+// marks it as failed when there are no attempts left.
+func (r *Reconciler) pipelineRunDispatchFailed(ctx context.Context, pr *tektonv1.PipelineRun, minion *v1alpha1.Minion, sendErr error) reconciler.Event {
+	attempt, delay, err := r.recordDispatchFailure(ctx, pr, sendErr, func(ctx context.Context, patch []byte) error {
+		_, err := r.clients.Tekton.TektonV1().PipelineRuns(pr.GetNamespace()).Patch(ctx, pr.GetName(), types.MergePatchType, patch, metav1.PatchOptions{})
+		return err
+	})
+	switch {
+	case errors.Is(err, errDispatchExhausted):
+		pr.Status.MarkFailed(failedReason(sendErr), "Cannot dispatch PipelineRun: %s", err.Error())
+		return reconciler.NewEvent(corev1.EventTypeWarning, failedReason(sendErr), err.Error())
+	case err != nil:
+		return err
+	}
+
+	logging.FromContext(ctx).Warnf("attempt %d to dispatch pipelinerun %s to minion %s failed, retrying in %s: %v", attempt, pr.GetName(), minion.GetName(), delay, sendErr)
+	controller.GetEventRecorder(ctx).Eventf(pr, corev1.EventTypeWarning, failureReason(sendErr), "Attempt %d to dispatch to minion %s failed, retrying in %s: %s", attempt, minion.GetName(), delay, sendErr.Error())
+	pr.Status.MarkRunning(ReasonDispatchRetrying, "Attempt %d to dispatch to minion %s failed, retrying in %s: %s", attempt, minion.GetName(), delay, sendErr.Error())
+	return controller.NewRequeueAfter(delay)
+}
+
+// taskRunDispatchFailed retries the dispatch of the TaskRun later or marks it
+// as failed when there are no attempts left.
+func (r *TaskRunReconciler) taskRunDispatchFailed(ctx context.Context, tr *tektonv1.TaskRun, minion *v1alpha1.Minion, sendErr error) reconciler.Event {
+	attempt, delay, err := r.recordDispatchFailure(ctx, tr, sendErr, func(ctx context.Context, patch []byte) error {
+		_, err := r.clients.Tekton.TektonV1().TaskRuns(tr.GetNamespace()).Patch(ctx, tr.GetName(), types.MergePatchType, patch, metav1.PatchOptions{})
+		return err
+	})
+	switch {
+	case errors.Is(err, errDispatchExhausted):
+		tr.Status.SetCondition(&apis.Condition{
+			Type:    apis.ConditionSucceeded,
+			Status:  corev1.ConditionFalse,
+			Reason:  failedReason(sendErr),
+			Message: fmt.Sprintf("Cannot dispatch TaskRun: %s", err.Error()),
+		})
+		return reconciler.NewEvent(corev1.EventTypeWarning, failedReason(sendErr), err.Error())
+	case err != nil:
+		return err
+	}
+
+	logging.FromContext(ctx).Warnf("attempt %d to dispatch taskrun %s to minion %s failed, retrying in %s: %v", attempt, tr.GetName(), minion.GetName(), delay, sendErr)
+	controller.GetEventRecorder(ctx).Eventf(tr, corev1.EventTypeWarning, failureReason(sendErr), "Attempt %d to dispatch to minion %s failed, retrying in %s: %s", attempt, minion.GetName(), delay, sendErr.Error())
+	markTaskRunWaiting(tr, ReasonDispatchRetrying, fmt.Sprintf("Attempt %d to dispatch to minion %s failed, retrying in %s: %s", attempt, minion.GetName(), delay, sendErr.Error()))
+	return controller.NewRequeueAfter(delay)
+}
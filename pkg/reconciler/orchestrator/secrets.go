@@ -0,0 +1,104 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/openshift-pipelines/tekton-armadas/pkg/apis/armada/v1alpha1"
+	"github.com/openshift-pipelines/tekton-armadas/pkg/seal"
+	atypes "github.com/openshift-pipelines/tekton-armadas/pkg/types"
+	tektonv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/logging"
+)
+
+// errNoPublicKey is returned when the run needs Secrets and the minion has
+// no public key to encrypt them with.
+var errNoPublicKey = errors.New("minion has no public key")
+
+// bundleSecrets returns the Secrets of the namespace the run needs on the
+// minion that opted in to be sent to it: the Secrets bound to the workspaces
+// and the image pull secrets of the service account of the run.
+func (r *Reconciler) bundleSecrets(ctx context.Context, namespace, serviceAccount string, workspaces []tektonv1.WorkspaceBinding) ([]*corev1.Secret, error) {
+	logger := logging.FromContext(ctx)
+	names := []string{}
+	for _, ws := range workspaces {
+		if ws.Secret != nil {
+			names = append(names, ws.Secret.SecretName)
+		}
+	}
+	if serviceAccount != "" {
+		sa, err := r.clients.Kube.CoreV1().ServiceAccounts(namespace).Get(ctx, serviceAccount, metav1.GetOptions{})
+		switch {
+		case apierrors.IsNotFound(err):
+			logger.Debugf("service account %s not found, expecting it on the minion", serviceAccount)
+		case err != nil:
+			return nil, fmt.Errorf("failed to get service account %s: %w", serviceAccount, err)
+		default:
+			for _, ref := range sa.ImagePullSecrets {
+				names = append(names, ref.Name)
+			}
+		}
+	}
+
+	secrets := []*corev1.Secret{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		secret, err := r.clients.Kube.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get secret %s: %w", name, err)
+		}
+		if secret.GetAnnotations()[AnnotationBundle] != "true" {
+			logger.Debugf("secret %s is not annotated with %s, expecting it on the minion", name, AnnotationBundle)
+			continue
+		}
+		secrets = append(secrets, secret)
+	}
+	return secrets, nil
+}
+
+// sealSecrets encrypts the Secrets with the public key of the minion, in the
+// public-key.pem key of its credentials Secret. Only the name, the labels,
+// the type and the data of the Secrets are sent.
+func (r *Reconciler) sealSecrets(ctx context.Context, minion *v1alpha1.Minion, secrets []*corev1.Secret) ([]atypes.SealedSecret, error) {
+	if len(secrets) == 0 {
+		return nil, nil
+	}
+	credentials, err := r.minionCredentials(ctx, minion)
+	if err != nil {
+		return nil, err
+	}
+	if credentials == nil || len(credentials.Data[seal.PublicKeyKey]) == 0 {
+		return nil, fmt.Errorf("%w: the run needs %d secrets and the credentials of minion %s have no %s key", errNoPublicKey, len(secrets), minion.GetName(), seal.PublicKeyKey)
+	}
+
+	sealed := make([]atypes.SealedSecret, 0, len(secrets))
+	for _, secret := range secrets {
+		data, err := json.Marshal(&corev1.Secret{
+			TypeMeta: metav1.TypeMeta{Kind: "Secret", APIVersion: corev1.SchemeGroupVersion.String()},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   secret.GetName(),
+				Labels: secret.GetLabels(),
+			},
+			Type: secret.Type,
+			Data: secret.Data,
+		})
+		if err != nil {
+			return nil, err
+		}
+		s, err := seal.Seal(credentials.Data[seal.PublicKeyKey], secret.GetName(), data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to seal secret %s for minion %s: %w", secret.GetName(), minion.GetName(), err)
+		}
+		sealed = append(sealed, s)
+	}
+	return sealed, nil
+}
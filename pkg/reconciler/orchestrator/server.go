@@ -0,0 +1,220 @@
+package orchestrator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	atypes "github.com/openshift-pipelines/tekton-armadas/pkg/types"
+	"knative.dev/pkg/logging"
+)
+
+const (
+	globalOrchestratorPort = "8082"
+	// the logs carry the bearer tokens of the users, they are only served
+	// over plain HTTP on the loopback interface, reached with port-forward
+	globalLogsAddress  = "127.0.0.1:8083"
+	httpTimeoutHandler = 60 * time.Second
+)
+
+// Response is the body answered to the minions.
+type Response struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+func writeResponse(ctx context.Context, response http.ResponseWriter, statusCode int, message string) {
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(statusCode)
+	if err := json.NewEncoder(response).Encode(Response{Status: statusCode, Message: message}); err != nil {
+		logging.FromContext(ctx).Errorf("failed to write back response: %v", err)
+	}
+}
+
+// handleEvent receives the events sent by the minions.
+func (r *Reconciler) handleEvent(ctx context.Context) http.HandlerFunc {
+	logger := logging.FromContext(ctx)
+	return func(response http.ResponseWriter, request *http.Request) {
+		ctx := r.configStore.ToContext(ctx)
+		if request.Method != http.MethodPost {
+			writeResponse(ctx, response, http.StatusOK, "ok")
+			return
+		}
+
+		// keep the body to verify the signature of the minions in pull mode
+		body, err := io.ReadAll(request.Body)
+		if err != nil {
+			writeResponse(ctx, response, http.StatusBadRequest, "cannot read request body")
+			return
+		}
+		request.Body = io.NopCloser(bytes.NewReader(body))
+
+		event, err := cloudevents.NewEventFromHTTPRequest(request)
+		if err != nil {
+			logger.Errorf("failed to create event from request: %v", err)
+			writeResponse(ctx, response, http.StatusBadRequest, "invalid cloudevent")
+			return
+		}
+
+		switch event.Type() {
+		case atypes.EventTypeStatus:
+			se := atypes.ArmadaStatusEvent{}
+			if err := event.DataAs(&se); err != nil {
+				logger.Errorf("failed to convert event data: %v", err)
+				writeResponse(ctx, response, http.StatusBadRequest, "invalid status event data")
+				return
+			}
+			minion, err := r.signedMinion(ctx, se.Minion, request, event.Type(), body)
+			if err != nil {
+				writePullError(ctx, response, err)
+				return
+			}
+			if err := r.mirrorStatus(ctx, minion, &se); err != nil {
+				logger.Errorf("failed to mirror status of run %s/%s: %v", se.Namespace, se.Name, err)
+				if errors.Is(err, errWrongMinion) {
+					writeResponse(ctx, response, http.StatusForbidden, err.Error())
+					return
+				}
+				writeResponse(ctx, response, http.StatusInternalServerError, "failed to mirror status")
+				return
+			}
+		case atypes.EventTypePull:
+			r.handlePull(ctx, response, request, *event, body)
+			return
+		case atypes.EventTypeAck:
+			r.handleAck(ctx, response, request, *event, body)
+			return
+		case atypes.EventTypeHeartbeat:
+			r.handleHeartbeat(ctx, response, request, *event, body)
+			return
+		default:
+			writeResponse(ctx, response, http.StatusBadRequest, fmt.Sprintf("unknown event type %s", event.Type()))
+			return
+		}
+
+		writeResponse(ctx, response, http.StatusAccepted, "accepted")
+	}
+}
+
+// tlsFiles returns the certificate and key files set in the environment
+// variables with the prefix, both or none of them must be set.
+func tlsFiles(prefix string) (string, string, error) {
+	certFile, keyFile := os.Getenv(prefix+"_TLS_CERT"), os.Getenv(prefix+"_TLS_KEY")
+	if (certFile == "") != (keyFile == "") {
+		return "", "", fmt.Errorf("both %s_TLS_CERT and %s_TLS_KEY must be set", prefix, prefix)
+	}
+	return certFile, keyFile, nil
+}
+
+// startServer serves the endpoint the minions send their events to, over TLS
+// when a certificate is set as the minions in pull mode often reach it over
+// untrusted networks.
+func (r *Reconciler) startServer(ctx context.Context) {
+	logger := logging.FromContext(ctx)
+	port := globalOrchestratorPort
+	if envPort := os.Getenv("ARMADA_ORCHESTRATOR_PORT"); envPort != "" {
+		port = envPort
+	}
+	certFile, keyFile, err := tlsFiles("ARMADA_ORCHESTRATOR")
+	if err != nil {
+		logger.Errorf("not listening for minion events: %v", err)
+		return
+	}
+	if certFile == "" {
+		logger.Warn("ARMADA_ORCHESTRATOR_TLS_CERT is not set, the minion events are received over plain HTTP")
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/live", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = fmt.Fprint(w, "ok")
+	})
+	mux.HandleFunc("/", r.handleEvent(ctx))
+
+	srv := &http.Server{
+		Addr:              ":" + port,
+		Handler:           http.TimeoutHandler(mux, httpTimeoutHandler, "Listener Timeout!\n"),
+		ReadHeaderTimeout: httpTimeoutHandler,
+	}
+
+	logger.Infof("listening for minion events on port %s (tls: %t)", port, certFile != "")
+	if err := serve(ctx, srv, certFile, keyFile); err != nil {
+		logger.Errorf("orchestrator server failed: %v", err)
+	}
+}
+
+// startLogsServer serves the logs of the orchestrated PipelineRuns. The users
+// authenticate with a bearer token, so the logs are served over TLS when a
+// certificate is set and only on a loopback address otherwise.
+func (r *Reconciler) startLogsServer(ctx context.Context) {
+	logger := logging.FromContext(ctx)
+	address := globalLogsAddress
+	if envAddress := os.Getenv("ARMADA_ORCHESTRATOR_LOGS_ADDRESS"); envAddress != "" {
+		address = envAddress
+	}
+	certFile, keyFile, err := tlsFiles("ARMADA_ORCHESTRATOR_LOGS")
+	if err != nil {
+		logger.Errorf("not serving logs: %v", err)
+		return
+	}
+	if certFile == "" && !isLoopback(address) {
+		logger.Errorf("not serving logs on %s without TLS: set ARMADA_ORCHESTRATOR_LOGS_TLS_CERT and ARMADA_ORCHESTRATOR_LOGS_TLS_KEY or listen on a loopback address", address)
+		return
+	}
+
+	// the logs are streamed, they are not subject to the timeout
+	mux := http.NewServeMux()
+	mux.Handle("GET /logs/{namespace}/{name}", r.handleLogs(ctx))
+
+	srv := &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: httpTimeoutHandler,
+	}
+
+	logger.Infof("serving logs on %s (tls: %t)", address, certFile != "")
+	if err := serve(ctx, srv, certFile, keyFile); err != nil {
+		logger.Errorf("logs server failed: %v", err)
+	}
+}
+
+// isLoopback checks if the address only listens on the loopback interface.
+func isLoopback(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// serve runs the server until the context is done, over TLS when the
+// certificate and key files are set.
+func serve(ctx context.Context, srv *http.Server, certFile, keyFile string) error {
+	go func() {
+		<-ctx.Done()
+		_ = srv.Shutdown(context.Background())
+	}()
+
+	var err error
+	if certFile != "" {
+		err = srv.ListenAndServeTLS(certFile, keyFile)
+	} else {
+		err = srv.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
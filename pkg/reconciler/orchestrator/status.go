@@ -0,0 +1,157 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/openshift-pipelines/tekton-armadas/pkg/apis/armada/v1alpha1"
+	atypes "github.com/openshift-pipelines/tekton-armadas/pkg/types"
+	tektonv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/reconciler"
+)
+
+// errWrongMinion is returned when a minion reports the status of a run that
+// has not been dispatched to it.
+var errWrongMinion = errors.New("run has not been dispatched to the minion")
+
+// isDone checks if the Succeeded condition is final.
+func isDone(cond *apis.Condition) bool {
+	return cond != nil && cond.Status != corev1.ConditionUnknown
+}
+
+// markDispatched records on the source PipelineRun that it has been sent to
+// the minion. The status is written straight away rather than through the
+// reconciler so it never overrides a status the minion already reported.
+func (r *Reconciler) markDispatched(ctx context.Context, pr *tektonv1.PipelineRun, minion *v1alpha1.Minion, eventID string) error {
+	patch, err := dispatchedPatch(minion, eventID)
+	if err != nil {
+		return err
+	}
+	if _, err := r.clients.Tekton.TektonV1().PipelineRuns(pr.GetNamespace()).Patch(ctx, pr.GetName(), types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to annotate pipelinerun with its minion: %w", err)
+	}
+	r.recent.record(minion.GetName(), runKey(pr), time.Now())
+
+	return reconciler.RetryUpdateConflicts(func(int) error {
+		latest, err := r.clients.Tekton.TektonV1().PipelineRuns(pr.GetNamespace()).Get(ctx, pr.GetName(), metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if !isWaitingForDispatch(latest) && !isAssigned(latest.Spec.Status == tektonv1.PipelineRunSpecStatusPending, latest.Status.GetCondition(apis.ConditionSucceeded)) {
+			return nil
+		}
+		latest.Status.MarkRunning(ReasonDispatched, "PipelineRun has been dispatched to minion %s", minion.GetName())
+		_, err = r.clients.Tekton.TektonV1().PipelineRuns(latest.GetNamespace()).UpdateStatus(ctx, latest, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// mirrorStatus copies the status reported by the minion onto the source
+// PipelineRun, as long as it has been dispatched to that minion.
+func (r *Reconciler) mirrorStatus(ctx context.Context, minion *v1alpha1.Minion, se *atypes.ArmadaStatusEvent) error {
+	if se.TaskRunStatus != nil {
+		return r.mirrorTaskRunStatus(ctx, minion, se)
+	}
+	logger := logging.FromContext(ctx)
+	return reconciler.RetryUpdateConflicts(func(int) error {
+		pr, err := r.clients.Tekton.TektonV1().PipelineRuns(se.Namespace).Get(ctx, se.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if !isOrchestrated(pr) {
+			return fmt.Errorf("pipelinerun %s/%s is not orchestrated", se.Namespace, se.Name)
+		}
+		if assigned := pr.GetAnnotations()[AnnotationMinion]; assigned != minion.GetName() {
+			return fmt.Errorf("%w: pipelinerun %s/%s is assigned to %q, not %s", errWrongMinion, se.Namespace, se.Name, assigned, minion.GetName())
+		}
+		// events may arrive out of order, never go back from a final status
+		if isDone(pr.Status.GetCondition(apis.ConditionSucceeded)) && !isDone(se.Status.GetCondition(apis.ConditionSucceeded)) {
+			logger.Debugf("ignoring stale status for pipelinerun %s/%s", se.Namespace, se.Name)
+			return nil
+		}
+
+		annotations := map[string]any{}
+		if se.RemoteName != "" && pr.GetAnnotations()[AnnotationRemoteName] != se.RemoteName {
+			annotations[AnnotationRemoteName] = se.RemoteName
+		}
+		if len(se.TaskResults) > 0 {
+			taskResults, err := json.Marshal(se.TaskResults)
+			if err != nil {
+				return err
+			}
+			if pr.GetAnnotations()[AnnotationTaskResults] != string(taskResults) {
+				annotations[AnnotationTaskResults] = string(taskResults)
+			}
+		}
+		if len(annotations) > 0 {
+			patch, err := annotationsPatch(annotations)
+			if err != nil {
+				return err
+			}
+			if pr, err = r.clients.Tekton.TektonV1().PipelineRuns(pr.GetNamespace()).Patch(ctx, pr.GetName(), types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+				return fmt.Errorf("failed to annotate pipelinerun with its remote name and task results: %w", err)
+			}
+		}
+
+		pr.Status.Conditions = se.Status.Conditions
+		pr.Status.StartTime = se.Status.StartTime
+		pr.Status.CompletionTime = se.Status.CompletionTime
+		pr.Status.ChildReferences = se.Status.ChildReferences
+		pr.Status.Results = se.Status.Results
+		_, err = r.clients.Tekton.TektonV1().PipelineRuns(pr.GetNamespace()).UpdateStatus(ctx, pr, metav1.UpdateOptions{})
+		if err == nil {
+			logger.Infof("mirrored status of pipelinerun %s/%s from %s/%s on the minion", se.Namespace, se.Name, se.RemoteNamespace, se.RemoteName)
+		}
+		return err
+	})
+}
+
+// mirrorTaskRunStatus copies the status reported by the minion onto the
+// source TaskRun, the same way as mirrorStatus for the PipelineRuns.
+func (r *Reconciler) mirrorTaskRunStatus(ctx context.Context, minion *v1alpha1.Minion, se *atypes.ArmadaStatusEvent) error {
+	logger := logging.FromContext(ctx)
+	return reconciler.RetryUpdateConflicts(func(int) error {
+		tr, err := r.clients.Tekton.TektonV1().TaskRuns(se.Namespace).Get(ctx, se.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if !isOrchestrated(tr) {
+			return fmt.Errorf("taskrun %s/%s is not orchestrated", se.Namespace, se.Name)
+		}
+		if assigned := tr.GetAnnotations()[AnnotationMinion]; assigned != minion.GetName() {
+			return fmt.Errorf("%w: taskrun %s/%s is assigned to %q, not %s", errWrongMinion, se.Namespace, se.Name, assigned, minion.GetName())
+		}
+		if isDone(tr.Status.GetCondition(apis.ConditionSucceeded)) && !isDone(se.TaskRunStatus.GetCondition(apis.ConditionSucceeded)) {
+			logger.Debugf("ignoring stale status for taskrun %s/%s", se.Namespace, se.Name)
+			return nil
+		}
+
+		if se.RemoteName != "" && tr.GetAnnotations()[AnnotationRemoteName] != se.RemoteName {
+			patch, err := annotationsPatch(map[string]any{AnnotationRemoteName: se.RemoteName})
+			if err != nil {
+				return err
+			}
+			if tr, err = r.clients.Tekton.TektonV1().TaskRuns(tr.GetNamespace()).Patch(ctx, tr.GetName(), types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+				return fmt.Errorf("failed to annotate taskrun with its remote name: %w", err)
+			}
+		}
+
+		tr.Status.Conditions = se.TaskRunStatus.Conditions
+		tr.Status.StartTime = se.TaskRunStatus.StartTime
+		tr.Status.CompletionTime = se.TaskRunStatus.CompletionTime
+		tr.Status.Results = se.TaskRunStatus.Results
+		_, err = r.clients.Tekton.TektonV1().TaskRuns(tr.GetNamespace()).UpdateStatus(ctx, tr, metav1.UpdateOptions{})
+		if err == nil {
+			logger.Infof("mirrored status of taskrun %s/%s from %s/%s on the minion", se.Namespace, se.Name, se.RemoteNamespace, se.RemoteName)
+		}
+		return err
+	})
+}
@@ -0,0 +1,208 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/openshift-pipelines/tekton-armadas/pkg/apis/armada/v1alpha1"
+	minionInformerv1alpha1 "github.com/openshift-pipelines/tekton-armadas/pkg/client/injection/informers/armada/v1alpha1/minion"
+	atypes "github.com/openshift-pipelines/tekton-armadas/pkg/types"
+	pipelineapi "github.com/tektoncd/pipeline/pkg/apis/pipeline"
+	tektonv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	tektonTaskRunInformerv1 "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1/taskrun"
+	tektonTaskRunReconcilerv1 "github.com/tektoncd/pipeline/pkg/client/injection/reconciler/pipeline/v1/taskrun"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/configmap"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/reconciler"
+)
+
+// TaskRunReconciler dispatches the orchestrated TaskRuns to the minions.
+type TaskRunReconciler struct {
+	*Reconciler
+}
+
+// NewTaskRunReconciler creates a TaskRunReconciler and returns the result of NewImpl.
+func NewTaskRunReconciler(ctx context.Context, cmw configmap.Watcher) *controller.Impl {
+	taskRunInformer := tektonTaskRunInformerv1.Get(ctx)
+	minionInformer := minionInformerv1alpha1.Get(ctx)
+
+	r := &TaskRunReconciler{Reconciler: sharedReconciler(ctx, cmw)}
+	impl := tektonTaskRunReconcilerv1.NewImpl(ctx, r, ctrlOpts(r.configStore))
+
+	if _, err := taskRunInformer.Informer().AddEventHandler(controller.HandleAll(checkStateAndEnqueue(impl))); err != nil {
+		logging.FromContext(ctx).Panicf("Couldn't register TaskRun informer event handler: %+v", err)
+	}
+
+	// when the minions change, give another chance to the taskruns waiting to be dispatched
+	if _, err := minionInformer.Informer().AddEventHandler(minionEventHandler(func() {
+		impl.FilteredGlobalResync(isOrchestrated, taskRunInformer.Informer())
+	})); err != nil {
+		logging.FromContext(ctx).Panicf("Couldn't register Minion informer event handler: %+v", err)
+	}
+
+	return impl
+}
+
+// markTaskRunWaiting sets the reason the TaskRun cannot be dispatched yet.
+func markTaskRunWaiting(tr *tektonv1.TaskRun, reason, message string) {
+	tr.Status.SetCondition(&apis.Condition{
+		Type:    apis.ConditionSucceeded,
+		Status:  corev1.ConditionUnknown,
+		Reason:  reason,
+		Message: message,
+	})
+}
+
+// taskRunEvent returns the event dispatching the TaskRun with the resources
+// it needs to the minion.
+func (r *Reconciler) taskRunEvent(ctx context.Context, tr *tektonv1.TaskRun, minion *v1alpha1.Minion) (atypes.ArmadaEvent, error) {
+	tr = tr.DeepCopy()
+	tr.Kind = pipelineapi.TaskRunControllerName
+	tr.APIVersion = tektonv1.SchemeGroupVersion.String()
+	data, err := atypes.SerializeObjectYaml(tr)
+	if err != nil {
+		return atypes.ArmadaEvent{}, err
+	}
+
+	resources, err := r.bundleTaskRun(ctx, tr)
+	if err != nil {
+		return atypes.ArmadaEvent{}, fmt.Errorf("failed to bundle resources of taskrun: %w", err)
+	}
+	secrets, err := r.bundleSecrets(ctx, tr.GetNamespace(), tr.Spec.ServiceAccountName, tr.Spec.Workspaces)
+	if err != nil {
+		return atypes.ArmadaEvent{}, fmt.Errorf("failed to bundle secrets of taskrun: %w", err)
+	}
+	sealed, err := r.sealSecrets(ctx, minion, secrets)
+	if err != nil {
+		return atypes.ArmadaEvent{}, err
+	}
+
+	return r.armadaEvent(minion, tr, data, resources, sealed)
+}
+
+func (r *TaskRunReconciler) HandlePendingTaskRun(ctx context.Context, tr *tektonv1.TaskRun) reconciler.Event {
+	logger := logging.FromContext(ctx)
+	minion, err := r.getMinion(ctx, tr)
+	switch {
+	case errors.Is(err, errNoMatchingMinion):
+		markTaskRunWaiting(tr, ReasonNoMatchingMinion, fmt.Sprintf("Waiting for a minion to be available: %s", err.Error()))
+		controller.GetEventRecorder(ctx).Event(tr, corev1.EventTypeWarning, ReasonNoMatchingMinion, err.Error())
+		return controller.NewRequeueAfter(noMatchingMinionRequeue)
+	case errors.Is(err, errInvalidMinionSelector):
+		markTaskRunWaiting(tr, ReasonInvalidMinionSelector, fmt.Sprintf("Cannot dispatch TaskRun: %s", err.Error()))
+		return reconciler.NewEvent(corev1.EventTypeWarning, ReasonInvalidMinionSelector, err.Error())
+	case err != nil:
+		return err
+	}
+	if minion.IsPull() {
+		if err := r.assign(ctx, tr, minion); err != nil {
+			return fmt.Errorf("failed to assign taskrun: %w", err)
+		}
+		markTaskRunWaiting(tr, ReasonAssigned, fmt.Sprintf("Waiting for minion %s to pull the TaskRun", minion.GetName()))
+		return reconciler.NewEvent(corev1.EventTypeNormal, ReasonAssigned, "TaskRun has been assigned to minion %s", minion.GetName())
+	}
+	logger.Infof("TaskRun %s will be dispatched to minion %s", tr.GetName(), minion.GetName())
+
+	aevent, err := r.taskRunEvent(ctx, tr, minion)
+	switch {
+	case errors.Is(err, errNoPublicKey):
+		return r.taskRunDispatchFailed(ctx, tr, minion, err)
+	case err != nil:
+		return err
+	}
+
+	eventID, err := r.sendToMinion(ctx, minion, atypes.EventTypeDispatch, aevent)
+	if err != nil {
+		return r.taskRunDispatchFailed(ctx, tr, minion, err)
+	}
+	if err := r.markTaskRunDispatched(ctx, tr, minion, eventID); err != nil {
+		return fmt.Errorf("failed to mark taskrun as dispatched: %w", err)
+	}
+	logger.Infof("TaskRun %s has been dispatched to minion %s with event %s", tr.GetName(), minion.GetName(), eventID)
+
+	return reconciler.NewEvent(corev1.EventTypeNormal, ReasonDispatched, "TaskRun has been dispatched to minion %s", minion.GetName())
+}
+
+// isTaskRunPending checks if the TaskRun has been annotated to be dispatched.
+func isTaskRunPending(tr *tektonv1.TaskRun) bool {
+	return tr.GetAnnotations()[AnnotationPending] == "true"
+}
+
+// isTaskRunWaitingForDispatch checks if the TaskRun is pending and has not
+// been sent to a minion yet.
+func isTaskRunWaitingForDispatch(tr *tektonv1.TaskRun) bool {
+	if !isTaskRunPending(tr) || tr.IsCancelled() {
+		return false
+	}
+	cond := tr.Status.GetCondition(apis.ConditionSucceeded)
+	if cond == nil {
+		return true
+	}
+	return isPreDispatchReason(cond.Reason)
+}
+
+// markTaskRunDispatched records on the source TaskRun that it has been sent
+// to the minion, the same way as markDispatched for the PipelineRuns.
+func (r *Reconciler) markTaskRunDispatched(ctx context.Context, tr *tektonv1.TaskRun, minion *v1alpha1.Minion, eventID string) error {
+	patch, err := dispatchedPatch(minion, eventID)
+	if err != nil {
+		return err
+	}
+	if _, err := r.clients.Tekton.TektonV1().TaskRuns(tr.GetNamespace()).Patch(ctx, tr.GetName(), types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to annotate taskrun with its minion: %w", err)
+	}
+	r.recent.record(minion.GetName(), runKey(tr), time.Now())
+
+	return reconciler.RetryUpdateConflicts(func(int) error {
+		latest, err := r.clients.Tekton.TektonV1().TaskRuns(tr.GetNamespace()).Get(ctx, tr.GetName(), metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if !isTaskRunWaitingForDispatch(latest) && !isAssigned(isTaskRunPending(latest), latest.Status.GetCondition(apis.ConditionSucceeded)) {
+			return nil
+		}
+		markTaskRunWaiting(latest, ReasonDispatched, fmt.Sprintf("TaskRun has been dispatched to minion %s", minion.GetName()))
+		_, err = r.clients.Tekton.TektonV1().TaskRuns(latest.GetNamespace()).UpdateStatus(ctx, latest, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// ReconcileKind implements Interface.ReconcileKind.
+func (r *TaskRunReconciler) ReconcileKind(ctx context.Context, tr *tektonv1.TaskRun) reconciler.Event {
+	if tr.IsCancelled() {
+		return r.propagateTaskRunCancel(ctx, tr)
+	}
+	if !isTaskRunWaitingForDispatch(tr) {
+		return r.checkTaskRunMinion(ctx, tr)
+	}
+	if wait := dispatchRetryIn(tr, time.Now()); wait > 0 {
+		return controller.NewRequeueAfter(wait)
+	}
+	logging.FromContext(ctx).Infof("Reconciling TaskRun %s, status: %s", tr.GetName(), tr.Spec.Status)
+	return r.HandlePendingTaskRun(ctx, tr)
+}
+
+// FinalizeKind implements Interface.FinalizeKind, the finalizer is only
+// released once the minion acknowledged the deletion of the remote TaskRun,
+// the same way as for the PipelineRuns.
+func (r *TaskRunReconciler) FinalizeKind(ctx context.Context, tr *tektonv1.TaskRun) reconciler.Event {
+	minion, err := r.owningMinion(ctx, tr)
+	if err != nil || minion == nil {
+		return err
+	}
+	acknowledged, err := r.propagateControl(ctx, minion, tr, pipelineapi.TaskRunControllerName, "", controlDelete)
+	if err != nil {
+		return err
+	}
+	if !acknowledged {
+		return controller.NewRequeueAfter(ackTimeout)
+	}
+	return nil
+}
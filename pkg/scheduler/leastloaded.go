@@ -0,0 +1,44 @@
+package scheduler
+
+import (
+	"github.com/openshift-pipelines/tekton-armadas/pkg/apis/armada/v1alpha1"
+	"knative.dev/pkg/kmeta"
+)
+
+// LeastLoaded dispatches to the minion with the fewest runs in flight,
+// relative to its capacity when it has one.
+type LeastLoaded struct{}
+
+var _ Scheduler = (*LeastLoaded)(nil)
+
+func NewLeastLoaded() *LeastLoaded {
+	return &LeastLoaded{}
+}
+
+func (*LeastLoaded) Name() string {
+	return StrategyLeastLoaded
+}
+
+func (*LeastLoaded) Schedule(_ kmeta.Accessor, minions []*v1alpha1.Minion, loads Loads) (*v1alpha1.Minion, error) {
+	if len(minions) == 0 {
+		return nil, ErrNoMinion
+	}
+	var chosen *v1alpha1.Minion
+	var chosenLoad float64
+	for _, minion := range sortedByName(minions) {
+		if load := load(minion, loads[minion.GetName()]); chosen == nil || load < chosenLoad {
+			chosen, chosenLoad = minion, load
+		}
+	}
+	return chosen, nil
+}
+
+// load returns the ratio of the runs in flight over the capacity, or the
+// number of runs in flight when the minion has no capacity set.
+func load(minion *v1alpha1.Minion, inFlight int) float64 {
+	running := float64(inFlight)
+	if minion.Spec.Capacity > 0 {
+		return running / float64(minion.Spec.Capacity)
+	}
+	return running
+}
@@ -0,0 +1,37 @@
+package scheduler
+
+import (
+	"sync"
+
+	"github.com/openshift-pipelines/tekton-armadas/pkg/apis/armada/v1alpha1"
+	"knative.dev/pkg/kmeta"
+)
+
+// RoundRobin dispatches to each minion in turn.
+type RoundRobin struct {
+	mu   sync.Mutex
+	next int
+}
+
+var _ Scheduler = (*RoundRobin)(nil)
+
+func NewRoundRobin() *RoundRobin {
+	return &RoundRobin{}
+}
+
+func (*RoundRobin) Name() string {
+	return StrategyRoundRobin
+}
+
+func (s *RoundRobin) Schedule(_ kmeta.Accessor, minions []*v1alpha1.Minion, _ Loads) (*v1alpha1.Minion, error) {
+	if len(minions) == 0 {
+		return nil, ErrNoMinion
+	}
+	sorted := sortedByName(minions)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	minion := sorted[s.next%len(sorted)]
+	s.next = (s.next + 1) % len(sorted)
+	return minion, nil
+}
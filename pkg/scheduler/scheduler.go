@@ -0,0 +1,71 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/openshift-pipelines/tekton-armadas/pkg/apis/armada/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"knative.dev/pkg/kmeta"
+)
+
+const (
+	// ConfigName is the name of the ConfigMap selecting the scheduler strategy.
+	ConfigName = "config-scheduler"
+	// StrategyKey is the key in the ConfigMap holding the strategy name.
+	StrategyKey = "strategy"
+
+	StrategyRoundRobin  = "round-robin"
+	StrategyLeastLoaded = "least-loaded"
+	StrategyWeighted    = "weighted"
+
+	// DefaultStrategy is used when the ConfigMap does not specify any strategy.
+	DefaultStrategy = StrategyRoundRobin
+)
+
+// ErrNoMinion is returned when there is no minion to schedule on.
+var ErrNoMinion = errors.New("no minion to schedule on")
+
+// Loads are the number of runs in flight on each minion by name, the runs
+// dispatched to it which are not done yet.
+type Loads map[string]int
+
+// Scheduler chooses which minion a run is dispatched to out of the eligible
+// minions.
+type Scheduler interface {
+	// Name returns the name of the strategy.
+	Name() string
+	// Schedule returns the minion the run should be dispatched to.
+	Schedule(run kmeta.Accessor, minions []*v1alpha1.Minion, loads Loads) (*v1alpha1.Minion, error)
+}
+
+// New returns a scheduler for the strategy.
+func New(strategy string) (Scheduler, error) {
+	switch strategy {
+	case "", StrategyRoundRobin:
+		return NewRoundRobin(), nil
+	case StrategyLeastLoaded:
+		return NewLeastLoaded(), nil
+	case StrategyWeighted:
+		return NewWeighted(), nil
+	default:
+		return nil, fmt.Errorf("unknown scheduler strategy %q", strategy)
+	}
+}
+
+// NewFromConfigMap returns the scheduler selected in the ConfigMap.
+func NewFromConfigMap(cm *corev1.ConfigMap) (Scheduler, error) {
+	return New(cm.Data[StrategyKey])
+}
+
+// sortedByName returns a copy of the minions sorted by name so strategies
+// don't depend on the order of the lister.
+func sortedByName(minions []*v1alpha1.Minion) []*v1alpha1.Minion {
+	sorted := make([]*v1alpha1.Minion, len(minions))
+	copy(sorted, minions)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].GetName() < sorted[j].GetName()
+	})
+	return sorted
+}
@@ -0,0 +1,217 @@
+package scheduler
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/openshift-pipelines/tekton-armadas/pkg/apis/armada/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+)
+
+// newMinion returns a minion with the capacity and the weight.
+func newMinion(name string, capacity, weight int32) *v1alpha1.Minion {
+	return &v1alpha1.Minion{
+		ObjectMeta: metav1.ObjectMeta{Name: name, UID: k8stypes.UID(name + "-uid")},
+		Spec:       v1alpha1.MinionSpec{Capacity: capacity, Weight: weight},
+	}
+}
+
+// schedule returns the names of the minions chosen for n runs.
+func schedule(t *testing.T, s Scheduler, minions []*v1alpha1.Minion, loads Loads, n int) []string {
+	t.Helper()
+	chosen := []string{}
+	for range n {
+		minion, err := s.Schedule(nil, minions, loads)
+		if err != nil {
+			t.Fatalf("Schedule() = %v", err)
+		}
+		chosen = append(chosen, minion.GetName())
+	}
+	return chosen
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		strategy string
+		want     string
+		wantErr  bool
+	}{
+		{strategy: "", want: StrategyRoundRobin},
+		{strategy: StrategyRoundRobin, want: StrategyRoundRobin},
+		{strategy: StrategyLeastLoaded, want: StrategyLeastLoaded},
+		{strategy: StrategyWeighted, want: StrategyWeighted},
+		{strategy: "random", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			s, err := New(tt.strategy)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() = %v, wantErr %t", err, tt.wantErr)
+			}
+			if err == nil && s.Name() != tt.want {
+				t.Errorf("New().Name() = %s, want %s", s.Name(), tt.want)
+			}
+		})
+	}
+}
+
+func TestNoMinion(t *testing.T) {
+	for _, s := range []Scheduler{NewRoundRobin(), NewLeastLoaded(), NewWeighted()} {
+		t.Run(s.Name(), func(t *testing.T) {
+			if _, err := s.Schedule(nil, nil, nil); !errors.Is(err, ErrNoMinion) {
+				t.Errorf("Schedule() = %v, want %v", err, ErrNoMinion)
+			}
+		})
+	}
+}
+
+func TestRoundRobin(t *testing.T) {
+	tests := []struct {
+		name    string
+		minions []*v1alpha1.Minion
+		runs    int
+		want    []string
+	}{
+		{
+			name:    "single minion",
+			minions: []*v1alpha1.Minion{newMinion("a", 0, 0)},
+			runs:    3,
+			want:    []string{"a", "a", "a"},
+		},
+		{
+			name:    "in name order",
+			minions: []*v1alpha1.Minion{newMinion("c", 0, 0), newMinion("a", 0, 0), newMinion("b", 0, 0)},
+			runs:    4,
+			want:    []string{"a", "b", "c", "a"},
+		},
+		{
+			name:    "ignores the weights",
+			minions: []*v1alpha1.Minion{newMinion("a", 0, 5), newMinion("b", 0, 1)},
+			runs:    4,
+			want:    []string{"a", "b", "a", "b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := schedule(t, NewRoundRobin(), tt.minions, nil, tt.runs); !slices.Equal(got, tt.want) {
+				t.Errorf("Schedule() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLeastLoaded(t *testing.T) {
+	tests := []struct {
+		name    string
+		minions []*v1alpha1.Minion
+		loads   Loads
+		want    string
+	}{
+		{
+			name:    "fewest runs in flight",
+			minions: []*v1alpha1.Minion{newMinion("a", 0, 0), newMinion("b", 0, 0)},
+			loads:   Loads{"a": 3, "b": 1},
+			want:    "b",
+		},
+		{
+			name:    "no loads",
+			minions: []*v1alpha1.Minion{newMinion("b", 0, 0), newMinion("a", 0, 0)},
+			want:    "a",
+		},
+		{
+			name:    "tie broken by name",
+			minions: []*v1alpha1.Minion{newMinion("b", 0, 0), newMinion("a", 0, 0), newMinion("c", 0, 0)},
+			loads:   Loads{"a": 2, "b": 1, "c": 1},
+			want:    "b",
+		},
+		{
+			name:    "relative to the capacity",
+			minions: []*v1alpha1.Minion{newMinion("a", 2, 0), newMinion("b", 10, 0)},
+			loads:   Loads{"a": 1, "b": 4},
+			want:    "b",
+		},
+		{
+			name:    "tie on the ratio broken by name",
+			minions: []*v1alpha1.Minion{newMinion("b", 10, 0), newMinion("a", 2, 0)},
+			loads:   Loads{"a": 1, "b": 5},
+			want:    "a",
+		},
+		{
+			name:    "minion without capacity",
+			minions: []*v1alpha1.Minion{newMinion("a", 0, 0), newMinion("b", 4, 0)},
+			loads:   Loads{"a": 1, "b": 3},
+			want:    "b",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewLeastLoaded().Schedule(nil, tt.minions, tt.loads)
+			if err != nil {
+				t.Fatalf("Schedule() = %v", err)
+			}
+			if got.GetName() != tt.want {
+				t.Errorf("Schedule() = %s, want %s", got.GetName(), tt.want)
+			}
+		})
+	}
+}
+
+func TestWeighted(t *testing.T) {
+	tests := []struct {
+		name    string
+		minions []*v1alpha1.Minion
+		runs    int
+		want    []string
+	}{
+		{
+			name:    "equal weights",
+			minions: []*v1alpha1.Minion{newMinion("b", 0, 1), newMinion("a", 0, 1)},
+			runs:    4,
+			want:    []string{"a", "b", "a", "b"},
+		},
+		{
+			name:    "unset weights count as one",
+			minions: []*v1alpha1.Minion{newMinion("a", 0, 0), newMinion("b", 0, 1)},
+			runs:    2,
+			want:    []string{"a", "b"},
+		},
+		{
+			name:    "smooth distribution",
+			minions: []*v1alpha1.Minion{newMinion("a", 0, 5), newMinion("b", 0, 1), newMinion("c", 0, 1)},
+			runs:    7,
+			want:    []string{"a", "a", "b", "a", "c", "a", "a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := schedule(t, NewWeighted(), tt.minions, nil, tt.runs); !slices.Equal(got, tt.want) {
+				t.Errorf("Schedule() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWeightedForgetsMinions(t *testing.T) {
+	s := NewWeighted()
+	a, b := newMinion("a", 0, 3), newMinion("b", 0, 1)
+	schedule(t, s, []*v1alpha1.Minion{a, b}, nil, 2)
+
+	// b is deleted, its credit is forgotten
+	schedule(t, s, []*v1alpha1.Minion{a}, nil, 1)
+	if _, ok := s.current[minionKey(b)]; ok {
+		t.Errorf("the credit of the deleted minion is kept: %v", s.current)
+	}
+
+	// a is re-created with another UID, it does not inherit the credit
+	recreated := newMinion("a", 0, 3)
+	recreated.UID = "a-new-uid"
+	schedule(t, s, []*v1alpha1.Minion{recreated, b}, nil, 1)
+	if _, ok := s.current[minionKey(a)]; ok {
+		t.Errorf("the credit of the re-created minion is kept: %v", s.current)
+	}
+	if len(s.current) != 2 {
+		t.Errorf("current = %v, want the credit of 2 minions", s.current)
+	}
+}
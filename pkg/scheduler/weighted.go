@@ -0,0 +1,67 @@
+package scheduler
+
+import (
+	"sync"
+
+	"github.com/openshift-pipelines/tekton-armadas/pkg/apis/armada/v1alpha1"
+	"knative.dev/pkg/kmeta"
+)
+
+// Weighted dispatches to the minions proportionally to their weight, using
+// a smooth weighted round-robin so a heavy minion does not get all its runs
+// in a row.
+type Weighted struct {
+	mu      sync.Mutex
+	current map[string]int64
+}
+
+var _ Scheduler = (*Weighted)(nil)
+
+func NewWeighted() *Weighted {
+	return &Weighted{current: map[string]int64{}}
+}
+
+func (*Weighted) Name() string {
+	return StrategyWeighted
+}
+
+func (s *Weighted) Schedule(_ kmeta.Accessor, minions []*v1alpha1.Minion, _ Loads) (*v1alpha1.Minion, error) {
+	if len(minions) == 0 {
+		return nil, ErrNoMinion
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// only the eligible minions keep their credit, the deleted ones are
+	// forgotten and the re-created ones start over with a new UID
+	current := make(map[string]int64, len(minions))
+	var chosen *v1alpha1.Minion
+	var total int64
+	for _, minion := range sortedByName(minions) {
+		w := weight(minion)
+		total += w
+		current[minionKey(minion)] = s.current[minionKey(minion)] + w
+		if chosen == nil || current[minionKey(minion)] > current[minionKey(chosen)] {
+			chosen = minion
+		}
+	}
+	current[minionKey(chosen)] -= total
+	s.current = current
+	return chosen, nil
+}
+
+// minionKey identifies the minion across its re-creations.
+func minionKey(minion *v1alpha1.Minion) string {
+	if uid := minion.GetUID(); uid != "" {
+		return string(uid)
+	}
+	return minion.GetName()
+}
+
+func weight(minion *v1alpha1.Minion) int64 {
+	if minion.Spec.Weight <= 0 {
+		return 1
+	}
+	return int64(minion.Spec.Weight)
+}
@@ -0,0 +1,177 @@
+// Package seal encrypts the Secrets sent to a minion so only the minion can
+// read them: each Secret is encrypted with a random AES-256-GCM key, itself
+// encrypted with the RSA public key of the minion with RSA-OAEP-SHA256.
+package seal
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/openshift-pipelines/tekton-armadas/pkg/types"
+)
+
+const (
+	// PublicKeyKey is the key of the PEM encoded public key of the minion in
+	// the credentials Secret of the Minion on the orchestrator.
+	PublicKeyKey = "public-key.pem"
+	// PrivateKeyKey is the key of the PEM encoded private key in the
+	// encryption Secret of the minion.
+	PrivateKeyKey = "private-key.pem"
+
+	// minKeyBits is the size of the smallest RSA key accepted.
+	minKeyBits  = 2048
+	dataKeySize = 32
+)
+
+var (
+	ErrInvalidKey = errors.New("invalid key")
+	ErrWrongKey   = errors.New("sealed with another key")
+)
+
+// keyID returns the fingerprint of the public key.
+func keyID(key *rsa.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+// parsePublicKey parses a PEM encoded PKIX or PKCS #1 RSA public key.
+func parsePublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: no PEM data", ErrInvalidKey)
+	}
+	var key any
+	var err error
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidKey, err.Error())
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T is not a RSA public key", ErrInvalidKey, key)
+	}
+	if rsaKey.N.BitLen() < minKeyBits {
+		return nil, fmt.Errorf("%w: RSA keys need at least %d bits", ErrInvalidKey, minKeyBits)
+	}
+	return rsaKey, nil
+}
+
+// parsePrivateKey parses a PEM encoded PKCS #8 or PKCS #1 RSA private key.
+func parsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: no PEM data", ErrInvalidKey)
+	}
+	var key any
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidKey, err.Error())
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T is not a RSA private key", ErrInvalidKey, key)
+	}
+	return rsaKey, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Seal encrypts the Secret serialized in plaintext with the public key. The
+// name of the Secret is authenticated along with it so the sealed data
+// cannot be passed for another Secret.
+func Seal(publicKeyPEM []byte, name string, plaintext []byte) (types.SealedSecret, error) {
+	publicKey, err := parsePublicKey(publicKeyPEM)
+	if err != nil {
+		return types.SealedSecret{}, err
+	}
+	id, err := keyID(publicKey)
+	if err != nil {
+		return types.SealedSecret{}, err
+	}
+
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return types.SealedSecret{}, err
+	}
+	encryptedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, publicKey, dataKey, []byte(name))
+	if err != nil {
+		return types.SealedSecret{}, fmt.Errorf("failed to encrypt the data key: %w", err)
+	}
+
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return types.SealedSecret{}, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return types.SealedSecret{}, err
+	}
+	return types.SealedSecret{
+		Name:  name,
+		KeyID: id,
+		Key:   encryptedKey,
+		Data:  gcm.Seal(nonce, nonce, plaintext, []byte(name)),
+	}, nil
+}
+
+// Open decrypts the sealed Secret with the private key.
+func Open(privateKeyPEM []byte, sealed types.SealedSecret) ([]byte, error) {
+	privateKey, err := parsePrivateKey(privateKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+	id, err := keyID(&privateKey.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	if sealed.KeyID != id {
+		return nil, fmt.Errorf("%w: secret %s has been sealed with key %s, not %s", ErrWrongKey, sealed.Name, sealed.KeyID, id)
+	}
+
+	dataKey, err := rsa.DecryptOAEP(sha256.New(), nil, privateKey, sealed.Key, []byte(sealed.Name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt the data key of secret %s: %w", sealed.Name, err)
+	}
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed.Data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("failed to decrypt secret %s: data too short", sealed.Name)
+	}
+	nonce, ciphertext := sealed.Data[:gcm.NonceSize()], sealed.Data[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, []byte(sealed.Name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secret %s: %w", sealed.Name, err)
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,214 @@
+package seal
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"testing"
+
+	"github.com/openshift-pipelines/tekton-armadas/pkg/types"
+)
+
+// newKey returns the PEM encoded PKIX public key and PKCS #8 private key of
+// a new RSA key.
+func newKey(t *testing.T, bits int) ([]byte, []byte) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER})
+}
+
+// pkcs1 returns the PEM encoded PKCS #1 public and private keys of the PKCS
+// #8 private key.
+func pkcs1(t *testing.T, privateKeyPEM []byte) ([]byte, []byte) {
+	t.Helper()
+	key, err := parsePrivateKey(privateKeyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&key.PublicKey)}),
+		pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
+
+func TestSealOpen(t *testing.T) {
+	publicKey, privateKey := newKey(t, minKeyBits)
+	_, anotherPrivateKey := newKey(t, minKeyBits)
+	publicKeyPKCS1, privateKeyPKCS1 := pkcs1(t, privateKey)
+	plaintext := []byte(`{"apiVersion":"v1","kind":"Secret","metadata":{"name":"token"},"data":{"token":"czNjcjN0"}}`)
+
+	tests := []struct {
+		name       string
+		publicKey  []byte
+		privateKey []byte
+		tamper     func(*types.SealedSecret)
+		wantErr    error
+		wantFail   bool
+	}{
+		{
+			name:       "valid",
+			publicKey:  publicKey,
+			privateKey: privateKey,
+		},
+		{
+			name:       "pkcs1 keys",
+			publicKey:  publicKeyPKCS1,
+			privateKey: privateKeyPKCS1,
+		},
+		{
+			name:       "wrong key",
+			publicKey:  publicKey,
+			privateKey: anotherPrivateKey,
+			wantErr:    ErrWrongKey,
+		},
+		{
+			name:       "wrong key with the key id of the right one",
+			publicKey:  publicKey,
+			privateKey: anotherPrivateKey,
+			tamper: func(sealed *types.SealedSecret) {
+				key, _ := parsePrivateKey(anotherPrivateKey)
+				sealed.KeyID, _ = keyID(&key.PublicKey)
+			},
+			wantFail: true,
+		},
+		{
+			name:       "tampered ciphertext",
+			publicKey:  publicKey,
+			privateKey: privateKey,
+			tamper: func(sealed *types.SealedSecret) {
+				sealed.Data[len(sealed.Data)-1] ^= 0xff
+			},
+			wantFail: true,
+		},
+		{
+			name:       "tampered nonce",
+			publicKey:  publicKey,
+			privateKey: privateKey,
+			tamper: func(sealed *types.SealedSecret) {
+				sealed.Data[0] ^= 0xff
+			},
+			wantFail: true,
+		},
+		{
+			name:       "tampered data key",
+			publicKey:  publicKey,
+			privateKey: privateKey,
+			tamper: func(sealed *types.SealedSecret) {
+				sealed.Key[0] ^= 0xff
+			},
+			wantFail: true,
+		},
+		{
+			name:       "passed for another secret",
+			publicKey:  publicKey,
+			privateKey: privateKey,
+			tamper: func(sealed *types.SealedSecret) {
+				sealed.Name = "another"
+			},
+			wantFail: true,
+		},
+		{
+			name:       "truncated data",
+			publicKey:  publicKey,
+			privateKey: privateKey,
+			tamper: func(sealed *types.SealedSecret) {
+				sealed.Data = sealed.Data[:4]
+			},
+			wantFail: true,
+		},
+		{
+			name:       "invalid private key",
+			publicKey:  publicKey,
+			privateKey: []byte("not a key"),
+			wantErr:    ErrInvalidKey,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sealed, err := Seal(tt.publicKey, "token", plaintext)
+			if err != nil {
+				t.Fatalf("Seal() = %v", err)
+			}
+			if bytes.Contains(sealed.Data, plaintext) {
+				t.Fatal("Seal() kept the plaintext")
+			}
+			if tt.tamper != nil {
+				tt.tamper(&sealed)
+			}
+
+			opened, err := Open(tt.privateKey, sealed)
+			switch {
+			case tt.wantFail:
+				if err == nil {
+					t.Errorf("Open() = %q, want an error", opened)
+				}
+			case !errors.Is(err, tt.wantErr):
+				t.Errorf("Open() = %v, want %v", err, tt.wantErr)
+			case err == nil && !bytes.Equal(opened, plaintext):
+				t.Errorf("Open() = %q, want %q", opened, plaintext)
+			}
+		})
+	}
+}
+
+func TestSealRandomized(t *testing.T) {
+	publicKey, _ := newKey(t, minKeyBits)
+	first, err := Seal(publicKey, "token", []byte("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := Seal(publicKey, "token", []byte("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.KeyID != second.KeyID {
+		t.Errorf("key ids %s and %s differ for the same key", first.KeyID, second.KeyID)
+	}
+	if bytes.Equal(first.Key, second.Key) || bytes.Equal(first.Data, second.Data) {
+		t.Error("the same secret has been sealed twice with the same data key")
+	}
+}
+
+func TestSealInvalidKey(t *testing.T) {
+	smallPublicKey, _ := newKey(t, 1024)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecDER, err := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		publicKey []byte
+	}{
+		{name: "no PEM data", publicKey: []byte("not a key")},
+		{name: "garbage in PEM", publicKey: pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte("garbage")})},
+		{name: "too small", publicKey: smallPublicKey},
+		{name: "not RSA", publicKey: pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: ecDER})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Seal(tt.publicKey, "token", []byte("data")); !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("Seal() = %v, want %v", err, ErrInvalidKey)
+			}
+		})
+	}
+}
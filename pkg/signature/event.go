@@ -0,0 +1,47 @@
+package signature
+
+import (
+	"strconv"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	cetypes "github.com/cloudevents/sdk-go/v2/types"
+)
+
+const (
+	// ExtensionSignature is the extension attribute holding the signature of
+	// an event sent through a broker, which does not keep the headers.
+	ExtensionSignature = "armadasignature"
+	// ExtensionTimestamp is the extension attribute holding the unix time the
+	// event has been signed at.
+	ExtensionTimestamp = "armadatimestamp"
+)
+
+// EventExtensions returns the extension attributes of the event.
+func EventExtensions(event cloudevents.Event) Extensions {
+	return func(name string) string {
+		value, err := event.Context.GetExtension(name)
+		if err != nil {
+			return ""
+		}
+		s, err := cetypes.ToString(value)
+		if err != nil {
+			return ""
+		}
+		return s
+	}
+}
+
+// SignEvent signs the type, the signed extensions and the data of the event
+// with the secret in its extension attributes.
+func SignEvent(secret []byte, event *cloudevents.Event) {
+	timestamp := time.Now().Unix()
+	event.SetExtension(ExtensionTimestamp, strconv.FormatInt(timestamp, 10))
+	event.SetExtension(ExtensionSignature, Sign(secret, timestamp, event.Type(), EventExtensions(*event), event.Data()))
+}
+
+// VerifyEvent verifies the signature in the extension attributes of the event.
+func VerifyEvent(secret []byte, event cloudevents.Event, maxSkew time.Duration) error {
+	extension := EventExtensions(event)
+	return Verify(secret, extension(ExtensionTimestamp), event.Type(), extension, extension(ExtensionSignature), event.Data(), time.Now(), maxSkew)
+}
@@ -0,0 +1,155 @@
+// Package signature authenticates the requests between the orchestrator and
+// the minions with a HMAC-SHA256 of a shared secret over the timestamp, the
+// cloudevent type, the extension attributes changing how the data is read
+// and the body of the request.
+package signature
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openshift-pipelines/tekton-armadas/pkg/payload"
+)
+
+const (
+	// HeaderSignature holds the signature of the request as sha256=<hex>.
+	HeaderSignature = "X-Armada-Signature"
+	// HeaderTimestamp holds the unix time the request has been signed at.
+	HeaderTimestamp = "X-Armada-Timestamp"
+	// SecretKey is the key of the Secret holding the shared secret.
+	SecretKey = "hmac-secret"
+	// DefaultMaxSkew is how far in the past or the future a signed request
+	// is accepted, older requests are considered replayed.
+	DefaultMaxSkew = 5 * time.Minute
+
+	signaturePrefix = "sha256="
+	// ceTypeHeader is signed so a body cannot be replayed as another kind of event.
+	ceTypeHeader = "Ce-Type"
+	// ceHeaderPrefix prefixes the extension attributes in the headers of the
+	// cloudevents in binary mode.
+	ceHeaderPrefix = "Ce-"
+)
+
+// SignedExtensions are the extension attributes signed along with the type,
+// they tell how to decode and reassemble the data of the event.
+var SignedExtensions = []string{
+	payload.ExtensionEncoding,
+	payload.ExtensionChunkID,
+	payload.ExtensionChunkIndex,
+	payload.ExtensionChunkCount,
+	payload.ExtensionDigest,
+	payload.ExtensionEventType,
+	payload.ExtensionContentType,
+}
+
+// Extensions returns the value of an extension attribute, empty when it is
+// not set.
+type Extensions func(name string) string
+
+// RequestExtensions returns the extension attributes in the headers of a
+// request carrying a cloudevent in binary mode.
+func RequestExtensions(req *http.Request) Extensions {
+	return func(name string) string {
+		return req.Header.Get(ceHeaderPrefix + name)
+	}
+}
+
+var (
+	ErrMissingSignature = errors.New("missing signature")
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrStaleRequest     = errors.New("request timestamp outside of the accepted window")
+)
+
+// Sign returns the signature of the body, event type and signed extensions
+// at the timestamp. The extensions are written with their length in a fixed
+// order, set or not, so they cannot be moved to the body or to each other.
+func Sign(secret []byte, timestamp int64, eventType string, extensions Extensions, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	_, _ = fmt.Fprintf(mac, "%d.%s.", timestamp, eventType)
+	for _, name := range SignedExtensions {
+		value := ""
+		if extensions != nil {
+			value = extensions(name)
+		}
+		_, _ = fmt.Fprintf(mac, "%s=%d:%s.", name, len(value), value)
+	}
+	_, _ = mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of the body, event type and signed extensions
+// and that the timestamp is within maxSkew of now.
+func Verify(secret []byte, timestamp, eventType string, extensions Extensions, signature string, body []byte, now time.Time, maxSkew time.Duration) error {
+	if signature == "" || timestamp == "" {
+		return ErrMissingSignature
+	}
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: invalid timestamp %q", ErrInvalidSignature, timestamp)
+	}
+	if skew := now.Sub(time.Unix(ts, 0)); skew > maxSkew || skew < -maxSkew {
+		return fmt.Errorf("%w: signed %s ago", ErrStaleRequest, skew.Round(time.Second))
+	}
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return fmt.Errorf("%w: unsupported signature algorithm", ErrInvalidSignature)
+	}
+	expected := Sign(secret, ts, eventType, extensions, body)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// VerifyRequest verifies the signature of the request, the body is left
+// intact to be read again by the handler.
+func VerifyRequest(secret []byte, req *http.Request, maxSkew time.Duration) error {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read request body: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return Verify(secret, req.Header.Get(HeaderTimestamp), req.Header.Get(ceTypeHeader), RequestExtensions(req), req.Header.Get(HeaderSignature), body, time.Now(), maxSkew)
+}
+
+// Transport is a http.RoundTripper signing the requests with the secret.
+type Transport struct {
+	Secret []byte
+	Base   http.RoundTripper
+}
+
+var _ http.RoundTripper = (*Transport)(nil)
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		_ = req.Body.Close()
+	}
+
+	signed := req.Clone(req.Context())
+	signed.Body = io.NopCloser(bytes.NewReader(body))
+	signed.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	timestamp := time.Now().Unix()
+	signed.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	signed.Header.Set(HeaderSignature, Sign(t.Secret, timestamp, req.Header.Get(ceTypeHeader), RequestExtensions(req), body))
+
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(signed)
+}
@@ -0,0 +1,355 @@
+package signature
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/openshift-pipelines/tekton-armadas/pkg/payload"
+)
+
+// extensions returns the extension attributes of the map.
+func extensions(values map[string]string) Extensions {
+	return func(name string) string { return values[name] }
+}
+
+func TestVerify(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"minion":"minion-a"}`)
+	eventType := "armada.tekton.dev/v1/heartbeat"
+	chunk := map[string]string{
+		payload.ExtensionEncoding:   payload.EncodingGzip,
+		payload.ExtensionChunkID:    "id",
+		payload.ExtensionChunkIndex: "0",
+		payload.ExtensionChunkCount: "2",
+	}
+	signature := Sign(secret, now.Unix(), eventType, extensions(chunk), body)
+
+	tests := []struct {
+		name       string
+		secret     []byte
+		timestamp  string
+		eventType  string
+		extensions map[string]string
+		signature  string
+		body       []byte
+		wantErr    error
+	}{
+		{
+			name:       "valid",
+			secret:     secret,
+			timestamp:  strconv.FormatInt(now.Unix(), 10),
+			eventType:  eventType,
+			extensions: chunk,
+			signature:  signature,
+			body:       body,
+		},
+		{
+			name:       "tampered body",
+			secret:     secret,
+			timestamp:  strconv.FormatInt(now.Unix(), 10),
+			eventType:  eventType,
+			extensions: chunk,
+			signature:  signature,
+			body:       []byte(`{"minion":"minion-b"}`),
+			wantErr:    ErrInvalidSignature,
+		},
+		{
+			name:       "tampered type",
+			secret:     secret,
+			timestamp:  strconv.FormatInt(now.Unix(), 10),
+			eventType:  "armada.tekton.dev/v1/status",
+			extensions: chunk,
+			signature:  signature,
+			body:       body,
+			wantErr:    ErrInvalidSignature,
+		},
+		{
+			name:      "tampered encoding",
+			secret:    secret,
+			timestamp: strconv.FormatInt(now.Unix(), 10),
+			eventType: eventType,
+			extensions: map[string]string{
+				payload.ExtensionChunkID:    "id",
+				payload.ExtensionChunkIndex: "0",
+				payload.ExtensionChunkCount: "2",
+			},
+			signature: signature,
+			body:      body,
+			wantErr:   ErrInvalidSignature,
+		},
+		{
+			name:      "tampered chunk count",
+			secret:    secret,
+			timestamp: strconv.FormatInt(now.Unix(), 10),
+			eventType: eventType,
+			extensions: map[string]string{
+				payload.ExtensionEncoding:   payload.EncodingGzip,
+				payload.ExtensionChunkID:    "id",
+				payload.ExtensionChunkIndex: "0",
+				payload.ExtensionChunkCount: "200000",
+			},
+			signature: signature,
+			body:      body,
+			wantErr:   ErrInvalidSignature,
+		},
+		{
+			name:      "extension moved to the body",
+			secret:    secret,
+			timestamp: strconv.FormatInt(now.Unix(), 10),
+			eventType: eventType,
+			extensions: map[string]string{
+				payload.ExtensionChunkID:    "id",
+				payload.ExtensionChunkIndex: "0",
+				payload.ExtensionChunkCount: "2",
+			},
+			signature: signature,
+			body:      append([]byte(payload.ExtensionEncoding+"=4:gzip."), body...),
+			wantErr:   ErrInvalidSignature,
+		},
+		{
+			name:       "tampered timestamp",
+			secret:     secret,
+			timestamp:  strconv.FormatInt(now.Unix()-1, 10),
+			eventType:  eventType,
+			extensions: chunk,
+			signature:  signature,
+			body:       body,
+			wantErr:    ErrInvalidSignature,
+		},
+		{
+			name:       "expired timestamp",
+			secret:     secret,
+			timestamp:  strconv.FormatInt(now.Add(-DefaultMaxSkew-time.Second).Unix(), 10),
+			eventType:  eventType,
+			extensions: chunk,
+			signature:  Sign(secret, now.Add(-DefaultMaxSkew-time.Second).Unix(), eventType, extensions(chunk), body),
+			body:       body,
+			wantErr:    ErrStaleRequest,
+		},
+		{
+			name:       "timestamp in the future",
+			secret:     secret,
+			timestamp:  strconv.FormatInt(now.Add(DefaultMaxSkew+time.Second).Unix(), 10),
+			eventType:  eventType,
+			extensions: chunk,
+			signature:  Sign(secret, now.Add(DefaultMaxSkew+time.Second).Unix(), eventType, extensions(chunk), body),
+			body:       body,
+			wantErr:    ErrStaleRequest,
+		},
+		{
+			name:       "wrong secret",
+			secret:     []byte("another secret"),
+			timestamp:  strconv.FormatInt(now.Unix(), 10),
+			eventType:  eventType,
+			extensions: chunk,
+			signature:  signature,
+			body:       body,
+			wantErr:    ErrInvalidSignature,
+		},
+		{
+			name:       "missing signature",
+			secret:     secret,
+			timestamp:  strconv.FormatInt(now.Unix(), 10),
+			eventType:  eventType,
+			extensions: chunk,
+			body:       body,
+			wantErr:    ErrMissingSignature,
+		},
+		{
+			name:       "missing timestamp",
+			secret:     secret,
+			eventType:  eventType,
+			extensions: chunk,
+			signature:  signature,
+			body:       body,
+			wantErr:    ErrMissingSignature,
+		},
+		{
+			name:       "invalid timestamp",
+			secret:     secret,
+			timestamp:  "yesterday",
+			eventType:  eventType,
+			extensions: chunk,
+			signature:  signature,
+			body:       body,
+			wantErr:    ErrInvalidSignature,
+		},
+		{
+			name:       "unsupported algorithm",
+			secret:     secret,
+			timestamp:  strconv.FormatInt(now.Unix(), 10),
+			eventType:  eventType,
+			extensions: chunk,
+			signature:  "sha1=" + signature[len(signaturePrefix):],
+			body:       body,
+			wantErr:    ErrInvalidSignature,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.timestamp, tt.eventType, extensions(tt.extensions), tt.signature, tt.body, now, DefaultMaxSkew)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTransportVerifyRequest(t *testing.T) {
+	secret := []byte("secret")
+	body := []byte(`{"minion":"minion-a"}`)
+
+	tests := []struct {
+		name    string
+		secret  []byte
+		tamper  func(*http.Request)
+		wantErr error
+	}{
+		{
+			name:   "valid",
+			secret: secret,
+		},
+		{
+			name:    "wrong secret",
+			secret:  []byte("another secret"),
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:   "tampered type",
+			secret: secret,
+			tamper: func(req *http.Request) {
+				req.Header.Set(ceTypeHeader, "armada.tekton.dev/v1/status")
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:   "tampered chunk index",
+			secret: secret,
+			tamper: func(req *http.Request) {
+				req.Header.Set("Ce-"+payload.ExtensionChunkIndex, "1")
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:   "unsigned",
+			secret: secret,
+			tamper: func(req *http.Request) {
+				req.Header.Del(HeaderSignature)
+			},
+			wantErr: ErrMissingSignature,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var verifyErr error
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if tt.tamper != nil {
+					tt.tamper(req)
+				}
+				verifyErr = VerifyRequest(secret, req, DefaultMaxSkew)
+				w.WriteHeader(http.StatusAccepted)
+			}))
+			defer server.Close()
+
+			req, err := http.NewRequest(http.MethodPost, server.URL, bytes.NewReader(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set(ceTypeHeader, "armada.tekton.dev/v1/heartbeat")
+			req.Header.Set("Ce-"+payload.ExtensionChunkIndex, "0")
+			client := &http.Client{Transport: &Transport{Secret: tt.secret}}
+			response, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = response.Body.Close()
+			if !errors.Is(verifyErr, tt.wantErr) {
+				t.Errorf("VerifyRequest() = %v, want %v", verifyErr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyEvent(t *testing.T) {
+	secret := []byte("secret")
+	newEvent := func() cloudevents.Event {
+		event := cloudevents.NewEvent()
+		event.SetID("id")
+		event.SetSource("armada")
+		event.SetType("armada.tekton.dev/v1/dispatch")
+		if err := event.SetData(cloudevents.ApplicationJSON, map[string]string{"name": "run"}); err != nil {
+			t.Fatal(err)
+		}
+		return event
+	}
+
+	tests := []struct {
+		name    string
+		tamper  func(*cloudevents.Event)
+		secret  []byte
+		wantErr error
+	}{
+		{
+			name:   "valid",
+			secret: secret,
+		},
+		{
+			name:   "tampered body",
+			secret: secret,
+			tamper: func(event *cloudevents.Event) {
+				_ = event.SetData(cloudevents.ApplicationJSON, map[string]string{"name": "another"})
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:   "tampered type",
+			secret: secret,
+			tamper: func(event *cloudevents.Event) {
+				event.SetType("armada.tekton.dev/v1/delete")
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:   "expired timestamp",
+			secret: secret,
+			tamper: func(event *cloudevents.Event) {
+				timestamp := time.Now().Add(-DefaultMaxSkew - time.Minute).Unix()
+				event.SetExtension(ExtensionTimestamp, strconv.FormatInt(timestamp, 10))
+				event.SetExtension(ExtensionSignature, Sign(secret, timestamp, event.Type(), EventExtensions(*event), event.Data()))
+			},
+			wantErr: ErrStaleRequest,
+		},
+		{
+			name:   "tampered encoding",
+			secret: secret,
+			tamper: func(event *cloudevents.Event) {
+				event.SetExtension(payload.ExtensionEncoding, payload.EncodingZstd)
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "wrong secret",
+			secret:  []byte("another secret"),
+			wantErr: ErrInvalidSignature,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := newEvent()
+			SignEvent(secret, &event)
+			if tt.tamper != nil {
+				tt.tamper(&event)
+			}
+			if err := VerifyEvent(tt.secret, event, DefaultMaxSkew); !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyEvent() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package types
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// SchemaVersion is the newest version of the ArmadaEvent.
+	SchemaVersion = "2.1"
+	// LegacySchemaVersion is the version of the ArmadaEvent without a schema
+	// version, sent to the minions not reporting one.
+	LegacySchemaVersion = "1.0"
+
+	// schemaURL is where the JSON Schemas of the ArmadaEvent are published,
+	// by major version.
+	schemaURL = "https://raw.githubusercontent.com/openshift-pipelines/tekton-armadas/main/pkg/types/schema/armada-event-v%d.json"
+)
+
+var (
+	// ErrUnsupportedSchemaVersion is returned for the events of a major
+	// version the minion does not know.
+	ErrUnsupportedSchemaVersion = errors.New("unsupported schema version")
+	// ErrInvalidArmadaEvent is returned for the events not matching the
+	// JSON Schema of their version.
+	ErrInvalidArmadaEvent = errors.New("invalid armada event")
+
+	//go:embed schema/armada-event-v2.json
+	schemaV2 string
+
+	// schemaVersions are the newest version of each supported major version.
+	schemaVersions = map[int]string{1: LegacySchemaVersion, 2: SchemaVersion}
+	// schemas are the JSON Schemas by major version, there is none for the
+	// version 1.
+	schemas = map[int]*jsonschema.Schema{
+		2: jsonschema.MustCompileString(DataSchema(SchemaVersion), schemaV2),
+	}
+)
+
+// SchemaMajor returns the major version of the schema version, 1 when it is
+// empty.
+func SchemaMajor(version string) (int, error) {
+	if version == "" {
+		return 1, nil
+	}
+	major, _, _ := strings.Cut(version, ".")
+	m, err := strconv.Atoi(major)
+	if err != nil || m <= 0 {
+		return 0, fmt.Errorf("%w %q: must be major.minor", ErrUnsupportedSchemaVersion, version)
+	}
+	return m, nil
+}
+
+// DataSchema returns the URL of the JSON Schema of the schema version, empty
+// for the version 1.
+func DataSchema(version string) string {
+	major, err := SchemaMajor(version)
+	if err != nil || major < 2 {
+		return ""
+	}
+	return fmt.Sprintf(schemaURL, major)
+}
+
+// NegotiateSchemaVersion returns the newest schema version supported by both
+// sides, out of the newest version the other side supports. The other side is
+// expected to support the older major versions.
+func NegotiateSchemaVersion(peer string) string {
+	major, err := SchemaMajor(peer)
+	if err != nil {
+		return LegacySchemaVersion
+	}
+	if version, ok := schemaVersions[major]; ok {
+		return version
+	}
+	if newest, _ := SchemaMajor(SchemaVersion); major > newest {
+		return SchemaVersion
+	}
+	return LegacySchemaVersion
+}
+
+// DecodeArmadaEvent decodes the data of a dispatch event, checking its major
+// version is supported and that it matches the JSON Schema of its version.
+func DecodeArmadaEvent(data []byte) (ArmadaEvent, error) {
+	aEvent := ArmadaEvent{}
+	if err := json.Unmarshal(data, &aEvent); err != nil {
+		return ArmadaEvent{}, fmt.Errorf("%w: %s", ErrInvalidArmadaEvent, err.Error())
+	}
+	major, err := SchemaMajor(aEvent.SchemaVersion)
+	if err != nil {
+		return ArmadaEvent{}, err
+	}
+	if _, ok := schemaVersions[major]; !ok {
+		return ArmadaEvent{}, fmt.Errorf("%w %s: supported versions are up to %s", ErrUnsupportedSchemaVersion, aEvent.SchemaVersion, SchemaVersion)
+	}
+
+	schema, ok := schemas[major]
+	if !ok {
+		return aEvent, nil
+	}
+	var v any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&v); err != nil {
+		return ArmadaEvent{}, fmt.Errorf("%w: %s", ErrInvalidArmadaEvent, err.Error())
+	}
+	if err := schema.Validate(v); err != nil {
+		return ArmadaEvent{}, fmt.Errorf("%w: %s", ErrInvalidArmadaEvent, err.Error())
+	}
+	return aEvent, nil
+}
+
+// YAMLs returns the base64 encoded yamls of the run and of its resources,
+// whatever the version of the event.
+func (e ArmadaEvent) YAMLs() []string {
+	yamls := []string{}
+	for _, run := range []string{e.PipelineRun, e.TaskRun} {
+		if run != "" {
+			yamls = append(yamls, run)
+		}
+	}
+	yamls = append(yamls, e.Resources...)
+	for _, doc := range e.Documents {
+		yamls = append(yamls, doc.Data)
+	}
+	return yamls
+}
+
+// NewDocument returns the document of a base64 encoded yaml serialized with
+// SerializeObjectYaml.
+func NewDocument(data string) (Document, error) {
+	decoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return Document{}, err
+	}
+	meta := struct {
+		APIVersion string `json:"apiVersion"`
+		Kind       string `json:"kind"`
+		Metadata   struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+	}{}
+	if err := yaml.Unmarshal(decoded, &meta); err != nil {
+		return Document{}, fmt.Errorf("failed to read document: %w", err)
+	}
+	return Document{APIVersion: meta.APIVersion, Kind: meta.Kind, Name: meta.Metadata.Name, Data: data}, nil
+}
@@ -0,0 +1,19 @@
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	ktypes "k8s.io/apimachinery/pkg/types"
+)
+
+// idempotencyKeyLength is the number of bytes of the hash kept in the key so
+// it fits in a label value.
+const idempotencyKeyLength = 20
+
+// IdempotencyKey returns the key of the dispatch of the run with the UID on
+// the cluster, usable as a label value.
+func IdempotencyKey(clusterID string, uid ktypes.UID) string {
+	sum := sha256.Sum256([]byte(clusterID + "/" + string(uid)))
+	return hex.EncodeToString(sum[:idempotencyKeyLength])
+}
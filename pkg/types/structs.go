@@ -1,6 +1,204 @@
 package types
 
+import (
+	tektonv1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// EventSource is the source of the events sent by armada.
+	EventSource = "https://github.com/openshift-pipelines/tekton-armadas"
+	// EventTypeDispatch is the type of the events sent by the orchestrator to
+	// dispatch a run to a minion.
+	EventTypeDispatch = "armada.tekton.dev/v1"
+	// EventTypeStatus is the type of the events sent by a minion to report the
+	// status of a run back to the orchestrator.
+	EventTypeStatus = "armada.tekton.dev/v1/status"
+	// EventTypeCancel is the type of the events sent by the orchestrator to
+	// cancel a run on a minion.
+	EventTypeCancel = "armada.tekton.dev/v1/cancel"
+	// EventTypeDelete is the type of the events sent by the orchestrator to
+	// delete a run on a minion.
+	EventTypeDelete = "armada.tekton.dev/v1/delete"
+	// EventTypePull is the type of the events sent by a minion in pull mode
+	// to wait for the events the orchestrator has for it.
+	EventTypePull = "armada.tekton.dev/v1/pull"
+	// EventTypeAck is the type of the events sent by a minion in pull mode
+	// to acknowledge the events it applied.
+	EventTypeAck = "armada.tekton.dev/v1/ack"
+	// EventTypeLogs is the type of the requests sent by the orchestrator to
+	// stream the logs of a run from a minion.
+	EventTypeLogs = "armada.tekton.dev/v1/logs"
+	// EventTypeHeartbeat is the type of the events sent periodically by a
+	// minion to report its health to the orchestrator.
+	EventTypeHeartbeat = "armada.tekton.dev/v1/heartbeat"
+	// EventTypeChunk is the type of the events carrying a chunk of a larger
+	// event, reassembled by the minion.
+	EventTypeChunk = "armada.tekton.dev/v1/chunk"
+
+	// ReasonServiceAccountNotFound is the reason of the response of a minion
+	// rejecting a run whose ServiceAccount does not exist.
+	ReasonServiceAccountNotFound = "ServiceAccountNotFound"
+
+	// ExtensionMinion is the extension attribute with the name of the minion
+	// the events published to a sink are routed to.
+	ExtensionMinion = "minion"
+)
+
+// ArmadaEvent dispatches a run to a minion. The events without a schema
+// version are of version 1, with the run in PipelineRun or TaskRun and its
+// resources in Resources. From version 2, the run and its resources are in
+// Documents.
 type ArmadaEvent struct {
-	PipelineRun string `json:"pipelineRun"`
-	Namespace   string `json:"namespace"`
+	// SchemaVersion is the version of the event, major.minor.
+	SchemaVersion string `json:"schemaVersion,omitempty"`
+	// ClusterID identifies the orchestrator cluster.
+	ClusterID string `json:"clusterID,omitempty"`
+
+	PipelineRun string `json:"pipelineRun,omitempty"`
+	// TaskRun is set instead of PipelineRun when a TaskRun is dispatched.
+	TaskRun   string `json:"taskRun,omitempty"`
+	Namespace string `json:"namespace"`
+	// Name is the name of the source run on the orchestrator.
+	Name string `json:"name,omitempty"`
+	// IdempotencyKey identifies the source run, a minion receiving the same
+	// key twice keeps the run it already created.
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
+	// Resources are the base64 encoded yamls of the resources the run
+	// needs, applied on the minion before the run.
+	Resources []string `json:"resources,omitempty"`
+
+	// Minion is the name of the minion the run is dispatched to, a minion
+	// knowing its name rejects the events of other minions.
+	Minion string `json:"minion,omitempty"`
+	// Documents are the run and the resources it needs.
+	Documents []Document `json:"documents,omitempty"`
+	// Options are how the minion runs the run.
+	Options *DispatchOptions `json:"options,omitempty"`
+
+	// Secrets are the Secrets the run needs, encrypted with the public key
+	// of the minion, created on the minion owned by the run.
+	Secrets []SealedSecret `json:"secrets,omitempty"`
+}
+
+// Document is a resource of an ArmadaEvent.
+type Document struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name,omitempty"`
+	// Data is the base64 encoded yaml of the resource.
+	Data string `json:"data"`
+}
+
+// SealedSecret is a Secret encrypted for a minion.
+type SealedSecret struct {
+	// Name is the name of the Secret.
+	Name string `json:"name"`
+	// KeyID is the fingerprint of the public key of the minion.
+	KeyID string `json:"keyID"`
+	// Key is the key the Secret is encrypted with, encrypted with the
+	// public key of the minion.
+	Key []byte `json:"key"`
+	// Data is the Secret as JSON encrypted with the key, after the nonce.
+	Data []byte `json:"data"`
+}
+
+// DispatchOptions are how the minion runs the run of an ArmadaEvent.
+type DispatchOptions struct {
+	// Tenant selects the namespace of the run on the minions mapping the
+	// namespaces by tenant, instead of the tenant label of the run.
+	Tenant string `json:"tenant,omitempty"`
+}
+
+// ArmadaStatusEvent reports the status of a run created by a minion.
+type ArmadaStatusEvent struct {
+	// Minion is the name of the Minion reporting the status, it must be the
+	// one the source run has been dispatched to.
+	Minion string `json:"minion"`
+	// Namespace and Name identify the source run on the orchestrator.
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// RemoteNamespace and RemoteName identify the run on the minion.
+	RemoteNamespace string `json:"remoteNamespace"`
+	RemoteName      string `json:"remoteName"`
+	// Status only carries the fields mirrored on the source PipelineRun.
+	Status tektonv1.PipelineRunStatus `json:"status"`
+	// TaskRunStatus is set instead of Status when the source run is a
+	// TaskRun, with only the fields mirrored on it.
+	TaskRunStatus *tektonv1.TaskRunStatus `json:"taskRunStatus,omitempty"`
+	// TaskResults are the results of the TaskRuns by pipeline task and
+	// result name.
+	TaskResults map[string]map[string]tektonv1.ResultValue `json:"taskResults,omitempty"`
+}
+
+// ArmadaControlEvent asks a minion to act on a run it created.
+type ArmadaControlEvent struct {
+	// Kind is the kind of the run, PipelineRun when empty.
+	Kind string `json:"kind,omitempty"`
+	// Namespace and Name identify the source run on the orchestrator.
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// IdempotencyKey is the key of the dispatch of the source run, the
+	// minion finds the run it created for it with it.
+	IdempotencyKey string `json:"idempotencyKey"`
+	// SpecStatus is the spec.status to set on the remote run when cancelling.
+	SpecStatus string `json:"specStatus,omitempty"`
+}
+
+// ArmadaPullEvent asks the orchestrator for the events of a minion in pull mode.
+type ArmadaPullEvent struct {
+	// Minion is the name of the Minion on the orchestrator.
+	Minion string `json:"minion"`
+}
+
+// ArmadaAckEvent acknowledges the events applied by a minion in pull mode.
+type ArmadaAckEvent struct {
+	// Minion is the name of the Minion on the orchestrator.
+	Minion string `json:"minion"`
+	// IDs are the IDs of the events applied by the minion.
+	IDs []string `json:"ids"`
+	// Failures are the events the minion rejected, they are not delivered
+	// again.
+	Failures []ArmadaAckFailure `json:"failures,omitempty"`
+}
+
+// ArmadaAckFailure is an event a minion in pull mode rejected.
+type ArmadaAckFailure struct {
+	// ID is the ID of the rejected event.
+	ID string `json:"id"`
+	// Reason is the machine readable reason of the rejection, if any.
+	Reason string `json:"reason,omitempty"`
+	// Message tells why the event has been rejected.
+	Message string `json:"message"`
+}
+
+// ArmadaLogsRequest asks a minion for the logs of a PipelineRun it created.
+type ArmadaLogsRequest struct {
+	// Namespace and Name identify the source PipelineRun on the orchestrator.
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// IdempotencyKey is the key of the dispatch of the source PipelineRun,
+	// the minion finds the PipelineRun it created for it with it.
+	IdempotencyKey string `json:"idempotencyKey"`
+	// Follow streams the logs until the PipelineRun is done.
+	Follow bool `json:"follow,omitempty"`
+}
+
+// ArmadaHeartbeatEvent reports the health of a minion.
+type ArmadaHeartbeatEvent struct {
+	// Minion is the name of the Minion on the orchestrator.
+	Minion string `json:"minion"`
+	// Version is the version of the minion controller.
+	Version string `json:"version,omitempty"`
+	// TektonVersion is the version of Tekton Pipelines on the minion.
+	TektonVersion string `json:"tektonVersion,omitempty"`
+	// RunningPipelineRuns and PendingPipelineRuns count the PipelineRuns
+	// created by the minion which are running or not started yet.
+	RunningPipelineRuns int32 `json:"runningPipelineRuns"`
+	PendingPipelineRuns int32 `json:"pendingPipelineRuns"`
+	// Allocatable is the sum of the allocatable resources of the nodes.
+	Allocatable corev1.ResourceList `json:"allocatable,omitempty"`
+	// SchemaVersion is the newest version of the ArmadaEvent the minion
+	// supports.
+	SchemaVersion string `json:"schemaVersion,omitempty"`
 }
@@ -43,7 +43,6 @@ func ReadTektonTypes(ctx context.Context, yamls []string) (Types, error) {
 		if err != nil {
 			return Types{}, err
 		}
-		fmt.Printf("string(bdata): %v\n", string(bdata))
 
 		for _, doc := range yamlDocSeparatorRe.Split(string(bdata), -1) {
 			if strings.TrimSpace(doc) == "" {
@@ -58,6 +57,8 @@ func ReadTektonTypes(ctx context.Context, yamls []string) (Types, error) {
 			switch o := obj.(type) {
 			case *tektonv1.PipelineRun:
 				types.Tekton.PipelineRuns = append(types.Tekton.PipelineRuns, o)
+			case *tektonv1.TaskRun:
+				types.Tekton.TaskRuns = append(types.Tekton.TaskRuns, o)
 			case *tektonv1.Pipeline:
 				types.Tekton.Pipelines = append(types.Tekton.Pipelines, o)
 			case *tektonv1.Task:
@@ -15,7 +15,6 @@ func objToMap(obj interface{}) map[string]interface{} {
 	unstructuredMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
 	unstructuredMap["Kind"] = unstructuredMap["kind"]
 	delete(unstructuredMap, "kind")
-	fmt.Printf("unstructuredMap: %v\n", unstructuredMap)
 	if err != nil {
 		log.Fatalf("Error converting object to map: %v", err)
 	}